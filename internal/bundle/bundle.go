@@ -1,16 +1,16 @@
 package bundle
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
 
+	"github.com/limoges/gatepeeker/internal/decoding"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	"github.com/open-policy-agent/gatekeeper/v3/apis"
 	"github.com/open-policy-agent/gatekeeper/v3/pkg/gator/reader"
-	yamlv3 "gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -81,6 +81,18 @@ func (t *ConstraintTemplate) resourceName() string {
 	return strings.ReplaceAll(fmt.Sprintf("%s:%s:%s:%s", apiVersion, kind, namespace, name), "/", ":")
 }
 
+// Title returns the title of the template, from its
+// metadata.gatekeeper.sh/title annotation, as used by the gatekeeper library.
+func (t *ConstraintTemplate) Title() string {
+	return t.GetAnnotations()["metadata.gatekeeper.sh/title"]
+}
+
+// Description returns the description of the template, from its description
+// annotation.
+func (t *ConstraintTemplate) Description() string {
+	return t.GetAnnotations()["description"]
+}
+
 func (t *ConstraintTemplate) GetObject() *templates.ConstraintTemplate {
 	return t.ConstraintTemplate
 }
@@ -98,51 +110,68 @@ func New() *Bundle {
 	return new(Bundle)
 }
 
-func splitYamlDocuments(buf []byte) [][]byte {
-	// split on document start; forget about document stop
-	docs := bytes.Split(buf, []byte("---"))
-	var out [][]byte
-	// only keep the valid documents
-	for i, doc := range docs {
-		var node yamlv3.Node
-		err := yamlv3.Unmarshal(doc, &node)
-		if err != nil {
-			continue
-		}
-		if node.Kind != 1 {
-			continue
-		}
-		out = append(out, docs[i])
-	}
-	return out
+// ParsePolicies returns the ConstraintTemplates and Constraints found in buf.
+// Documents which can't be decoded are logged and skipped, as well as other
+// objects.
+func ParsePolicies(buf []byte) (*Bundle, error) {
+	return parsePolicies(buf, false)
 }
 
-func ParsePolicies(buf []byte) (*Bundle, error) {
+// ParsePoliciesStrict is like ParsePolicies, but fails when a document can't
+// be decoded, lacks an apiVersion or kind, or isn't a ConstraintTemplate or a
+// Constraint. The returned error joins a *decoding.DocumentError for each.
+func ParsePoliciesStrict(buf []byte) (*Bundle, error) {
+	return parsePolicies(buf, true)
+}
+
+func parsePolicies(buf []byte, strict bool) (*Bundle, error) {
 
-	documents := splitYamlDocuments(buf)
+	documents, err := decoding.Decode(buf)
+	if err != nil && !strict {
+		slog.Error("failed parse kubernetes resource", "error", err)
+	}
 
 	var (
 		constraints []*Constraint
 		templates   []*ConstraintTemplate
+		errs        = []error{err}
 	)
+	reject := func(document *decoding.Document, err error) {
+		errs = append(errs, &decoding.DocumentError{Index: document.Index, Line: document.Line, Err: err})
+	}
 	for _, document := range documents {
-		slog.Debug("Document", "length", len(document))
-		obj, err := reader.ReadUnstructured(document)
-		if err != nil {
-			slog.Error("failed parse kubernetes resource", "error", err)
-			continue
-		}
+		slog.Debug("Document", "length", len(document.Raw))
+		obj := document.Object
 
 		switch {
+		case obj.GetAPIVersion() == "" || obj.GetKind() == "":
+			if !strict {
+				slog.Warn("skipping document without apiVersion or kind", "document", document.Index, "line", document.Line)
+				continue
+			}
+			reject(document, errors.New("missing apiVersion or kind"))
 		case reader.IsConstraint(obj):
-			constraints = append(constraints, newConstraint(obj, document))
+			constraints = append(constraints, newConstraint(obj, document.Raw))
 		case reader.IsTemplate(obj):
 			t, err := reader.ToTemplate(scheme, obj)
 			if err != nil {
-				panic(err)
+				if !strict {
+					slog.Error("failed to read ConstraintTemplate", "name", obj.GetName(), "error", err)
+					continue
+				}
+				reject(document, fmt.Errorf("invalid ConstraintTemplate %s: %w", obj.GetName(), err))
+				continue
 			}
 			t.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind()) // reader.ToTemplate doesn't seem to set GroupVersionKind
-			templates = append(templates, newConstraintTemplate(t, document))
+			templates = append(templates, newConstraintTemplate(t, document.Raw))
+		case strict:
+			reject(document, fmt.Errorf("unexpected %s %s, expected a ConstraintTemplate or a Constraint", obj.GroupVersionKind().String(), obj.GetName()))
+		}
+	}
+
+	if strict {
+		if err := errors.Join(errs...); err != nil {
+			return nil, err
 		}
 	}
 
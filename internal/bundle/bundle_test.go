@@ -0,0 +1,90 @@
+package bundle_test
+
+import (
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/bundle"
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoliciesStrict(t *testing.T) {
+	policies := `
+apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sRequiredLabels
+metadata:
+  name: all-must-have-owner
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: not-a-policy
+---
+metadata:
+  name: no-kind
+---
+kind: [
+`
+
+	b, err := bundle.ParsePolicies([]byte(policies))
+	require.NoError(t, err)
+	assert.Len(t, b.GetConstraints(), 1)
+
+	_, err = bundle.ParsePoliciesStrict([]byte(policies))
+	require.Error(t, err)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok)
+
+	var lines []int
+	for _, err := range flatten(joined.Unwrap()) {
+		var docErr *decoding.DocumentError
+		require.ErrorAs(t, err, &docErr)
+		lines = append(lines, docErr.Line)
+	}
+	assert.ElementsMatch(t, []int{7, 12, 15}, lines)
+	assert.ErrorContains(t, err, "unexpected apps/v1, Kind=Deployment not-a-policy")
+	assert.ErrorContains(t, err, "missing apiVersion or kind")
+}
+
+func flatten(errs []error) (out []error) {
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			out = append(out, flatten(joined.Unwrap())...)
+			continue
+		}
+		out = append(out, err)
+	}
+	return out
+}
+
+func TestParsePoliciesList(t *testing.T) {
+	policies := `apiVersion: v1
+kind: List
+items:
+- apiVersion: templates.gatekeeper.sh/v1
+  kind: ConstraintTemplate
+  metadata:
+    name: k8srequiredlabels
+  spec:
+    crd:
+      spec:
+        names:
+          kind: K8sRequiredLabels
+    targets:
+    - target: admission.k8s.gatekeeper.sh
+      rego: |
+        package k8srequiredlabels
+        violation[{"msg": "missing"}] { false }
+- apiVersion: constraints.gatekeeper.sh/v1beta1
+  kind: K8sRequiredLabels
+  metadata:
+    name: all-must-have-owner
+`
+
+	b, err := bundle.ParsePoliciesStrict([]byte(policies))
+	require.NoError(t, err)
+	assert.Len(t, b.GetConstraintTemplates(), 1)
+	assert.Len(t, b.GetConstraints(), 1)
+}
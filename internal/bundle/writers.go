@@ -72,13 +72,17 @@ func WriteYAML(b *Bundle) ([]byte, error) {
 
 	var buf bytes.Buffer
 	for _, obj := range objects {
-		// we don't write newline because raw may contains it
-		if _, err := buf.Write([]byte("---")); err != nil {
+		if _, err := buf.Write([]byte("---\n")); err != nil {
 			return nil, err
 		}
 		if _, err := buf.Write(obj); err != nil {
 			return nil, err
 		}
+		if !bytes.HasSuffix(obj, []byte("\n")) {
+			if _, err := buf.Write([]byte("\n")); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return buf.Bytes(), nil
 }
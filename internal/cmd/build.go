@@ -31,6 +31,9 @@ $ cat manifests.yaml | gatepeeker bundle > policies.yaml
 	cmd.Action = build
 	cmd.Flags = []cli.Flag{
 		flagPolicies,
+		flagPoliciesInclude,
+		flagPoliciesExclude,
+		flagStrict,
 		flagVerbose,
 	}
 	if os.Getenv("GATEPEEKER_EXPERIMENTAL") != "" {
@@ -53,30 +56,20 @@ func build(ctx context.Context, cmd *cli.Command) error {
 		return fmt.Errorf("failed to read stdin: %w", err)
 	}
 
-	slog.Info("Reading from stdin", "size", len(stdin))
 	if len(stdin) > 0 {
 		slog.Info("Reading from stdin", "size", len(stdin))
-		stdinBundle, err := bundle.ParsePolicies(stdin)
-		if err != nil {
-			return fmt.Errorf("failed to build bundle from yaml: %w", err)
+		stdinBundle, err := policyParser(cmd)(stdin)
+		if err := rejectDocuments(prefixErrors("stdin", err), nil); err != nil {
+			return err
 		}
 		b.Merge(stdinBundle)
 	}
 
-	urlstrs := cmd.StringSlice(flagPolicies.Name)
-	for _, urlstr := range urlstrs {
-
-		buf, err := readSource(urlstr)
-		if err != nil {
-			return fmt.Errorf("failed to read arg source: %w", err)
-		}
-
-		argBundle, err := bundle.ParsePolicies(buf)
-		if err != nil {
-			return fmt.Errorf("failed to build bundle from yaml: %w", err)
-		}
-		b.Merge(argBundle)
+	argBundle, err := loadPolicies(cmd)
+	if err != nil {
+		return err
 	}
+	b.Merge(argBundle)
 
 	buf, err := bundle.WriteYAML(b)
 	if err != nil {
@@ -5,26 +5,119 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
 	"log/slog"
-	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/hairyhenderson/go-fsimpl"
-	"github.com/hairyhenderson/go-fsimpl/blobfs"
-	"github.com/hairyhenderson/go-fsimpl/filefs"
-	"github.com/hairyhenderson/go-fsimpl/gitfs"
-	"github.com/hairyhenderson/go-fsimpl/httpfs"
 	"github.com/limoges/gatepeeker/internal/bundle"
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/loading"
+	"github.com/limoges/gatepeeker/internal/openapi"
+	"github.com/limoges/gatepeeker/internal/reporting"
 	"github.com/urfave/cli/v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 var (
 	flagPolicies = &cli.StringSliceFlag{
 		Name:  "policies",
-		Usage: "A location to load policies from",
+		Usage: "A file, directory, glob or URL to load policies from",
+		Value: []string{},
+	}
+	flagPoliciesInclude = &cli.StringSliceFlag{
+		Name:  "policies-include",
+		Usage: "Only read policy files matching a glob pattern, like \"**/template.yaml\"",
+		Value: []string{},
+	}
+	flagPoliciesExclude = &cli.StringSliceFlag{
+		Name:  "policies-exclude",
+		Usage: "Skip policy files and directories matching a glob pattern",
+		Value: []string{},
+	}
+	flagKustomize = &cli.StringSliceFlag{
+		Name:  "kustomize",
+		Usage: "Render a kustomization directory and validate its output",
+		Value: []string{},
+	}
+	flagHelmChart = &cli.StringSliceFlag{
+		Name:  "helm-chart",
+		Usage: "Render a local Helm chart, as `helm template` does, and validate its output",
+		Value: []string{},
+	}
+	flagValues = &cli.StringSliceFlag{
+		Name:  "values",
+		Usage: "A values file used for every render of --helm-chart",
+		Value: []string{},
+	}
+	flagValuesMatrix = &cli.StringSliceFlag{
+		Name:  "values-matrix",
+		Usage: "A glob pattern of values files to render --helm-chart with, one at a time. When repeated, every combination is rendered",
+		Value: []string{},
+	}
+	flagStrict = &cli.BoolFlag{
+		Name:  "strict",
+		Usage: "Fail on documents which can't be decoded, lack an apiVersion or kind, or aren't policies in a policy source",
+		Value: false,
+	}
+	flagApplyDefaults = &cli.BoolFlag{
+		Name:  "apply-defaults",
+		Usage: "Apply the defaults set by the Kubernetes API server to core, apps, batch and networking resources before review",
+		Value: false,
+	}
+	flagDetectDuplicates = &cli.BoolFlag{
+		Name:  "detect-duplicates",
+		Usage: "Deny resources sharing their apiVersion, kind, namespace and name with another resource of the same file or rendered output",
+		Value: false,
+	}
+	flagValidateSchemas = &cli.BoolFlag{
+		Name:  "validate-schemas",
+		Usage: "Check resources against the OpenAPI schemas of Kubernetes and of --crds before evaluating policies",
+		Value: false,
+	}
+	flagKubernetesVersion = &cli.StringFlag{
+		Name:  "kubernetes-version",
+		Usage: "The Kubernetes version whose schemas are used by --validate-schemas",
+		Value: openapi.DefaultVersion,
+	}
+	flagSchemaLocation = &cli.StringFlag{
+		Name:  "schema-location",
+		Usage: "A directory or URL holding the OpenAPI v3 documents of Kubernetes, {version} is replaced by --kubernetes-version. Defaults to the embedded documents of " + openapi.DefaultVersion + ", or to " + openapi.DefaultLocation + " for other versions",
+		Value: "",
+	}
+	flagCRDs = &cli.StringSliceFlag{
+		Name:  "crds",
+		Usage: "A file, directory, glob or URL to load CustomResourceDefinitions from, implies --validate-schemas",
+		Value: []string{},
+	}
+	flagOutput = &cli.StringSliceFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   "The format of the report: text, json, ctrf, junit, sarif, github, gitlab-codequality, markdown, html, or template=path.tmpl to execute a Go template. Use format=path, or template=path.tmpl=path, to write it to a file instead of stdout. Repeat to write several reports, at most one of them to stdout",
+		Value:   []string{"text"},
+	}
+	flagGroupBy = &cli.StringFlag{
+		Name:  "group-by",
+		Usage: "Group the resources of the text output by resource, constraint, file or namespace",
+		Value: reporting.TextGroupByResource,
+	}
+	flagJUnitSuites = &cli.StringFlag{
+		Name:  "junit-suites",
+		Usage: "Group the test cases of the junit output by file or by constraint",
+		Value: reporting.JUnitSuitesByFile,
+	}
+	flagMarkdownMaxSize = &cli.IntFlag{
+		Name:  "markdown-max-size",
+		Usage: "The size in bytes above which the details of the markdown output are truncated, 0 for no limit",
+		Value: reporting.MarkdownMaxSize,
+	}
+	flagNoRedact = &cli.BoolFlag{
+		Name:  "no-redact",
+		Usage: "Write the values of Secrets, and of --redact-path and --redact-pattern, to the reports as they are",
+		Value: false,
+	}
+	flagRedactPaths = &cli.StringSliceFlag{
+		Name:  "redact-path",
+		Usage: "A JSON path, like .spec.containers[*].env[*].value, to mask in reports and logs along with the data of Secrets, prefixed by a kind to restrict it, like ConfigMap:.data.token",
 		Value: []string{},
 	}
 	flagVerbose = &cli.BoolFlag{
@@ -65,163 +158,171 @@ func logging(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 
 }
 
-func getWorkingDirURL() string {
-	wd, _ := os.Getwd()
-	abs, _ := filepath.Abs(wd)
-	u := &url.URL{}
-	u.Path = abs
-	u.Scheme = "file"
-	return u.String()
-}
-
-func formatURL(in string) (searchPath string, err error) {
-	if in == "" {
-		wd, err := os.Getwd()
-		if err != nil {
-			return "", err
-		}
-		searchPath = fmt.Sprintf("file://%s", wd)
-	} else {
-		u, err := url.Parse(in)
-		if err != nil {
-			return "", fmt.Errorf("failed to parse: %w", err)
-		}
-		if u.Scheme == "" {
-			abs, err := filepath.Abs(in)
-			if err != nil {
-				return "", fmt.Errorf("failed to convert to absolute path: %w", err)
-			}
-			searchPath = fmt.Sprintf("file://%s", abs)
-		} else {
-			searchPath = u.String()
-		}
+// loadBundle reads the policies found at every --policies location, which
+// must hold templates and constraints to validate against.
+func loadBundle(cmd *cli.Command) (*bundle.Bundle, error) {
+	b, err := loadPolicies(cmd)
+	if err != nil {
+		return nil, err
 	}
-	return searchPath, nil
-}
 
-func fsFromURL(u string) (fs.FS, error) {
-	m := fsimpl.NewMux()
-	m.Add(filefs.FS)
-	m.Add(httpfs.FS)
-	m.Add(blobfs.FS)
-	m.Add(gitfs.FS)
-	return m.Lookup(u)
-}
+	if len(b.GetConstraintTemplates()) == 0 {
+		return nil, errors.New("no templates were found")
+	}
 
-func loadBundle(ctx context.Context, cmd *cli.Command) (*bundle.Bundle, error) {
-	urls := cmd.StringSlice(flagPolicies.Name)
+	if len(b.GetConstraints()) == 0 {
+		return nil, errors.New("no constraints were found")
+	}
 
-	b := bundle.New()
+	return b, nil
+}
 
-	for _, item := range urls {
-		u, err := formatURL(item)
+// loadPolicies reads the policies found at every --policies location.
+func loadPolicies(cmd *cli.Command) (*bundle.Bundle, error) {
+	l := loading.New()
+	l.Include = cmd.StringSlice(flagPoliciesInclude.Name)
+	l.Exclude = cmd.StringSlice(flagPoliciesExclude.Name)
+
+	var (
+		b        = bundle.New()
+		parse    = policyParser(cmd)
+		rejected []error
+	)
+	for _, source := range cmd.StringSlice(flagPolicies.Name) {
+		files, err := l.Load(source)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to read policies from %s: %w", source, err)
 		}
 
-		base, err := url.Parse(u)
-		if err != nil {
-			return nil, err
+		for _, f := range files {
+			slog.Info("Reading policies", "file", f.Name, "size", len(f.Data))
+			parsed, err := parse(f.Data)
+			if err != nil {
+				rejected = append(rejected, prefixErrors(f.Name, err)...)
+				continue
+			}
+			b.Merge(parsed)
 		}
+	}
+	if err := rejectDocuments(rejected, nil); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
 
-		filename := filepath.Base(base.Path)
-		dir := filepath.Dir(base.Path)
-
-		base.Path = dir
+// loadSchemas returns the schema validator configured by --validate-schemas
+// and --crds, or nil when schemas aren't validated.
+func loadSchemas(cmd *cli.Command) (*openapi.Validator, error) {
+	crds := cmd.StringSlice(flagCRDs.Name)
+	if !cmd.Bool(flagValidateSchemas.Name) && len(crds) == 0 {
+		return nil, nil
+	}
 
-		fsys, err := fsFromURL(base.String())
-		if err != nil {
-			return nil, err
-		}
+	v, err := openapi.NewValidator(cmd.String(flagSchemaLocation.Name), cmd.String(flagKubernetesVersion.Name))
+	if err != nil {
+		return nil, err
+	}
 
-		buf, err := fs.ReadFile(fsys, filename)
+	l := loading.New()
+	for _, source := range crds {
+		files, err := l.Load(source)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to read CustomResourceDefinitions from %s: %w", source, err)
 		}
 
-		parsed, err := bundle.ParsePolicies(buf)
-		if err != nil {
-			return nil, err
+		for _, f := range files {
+			slog.Info("Reading CustomResourceDefinitions", "file", f.Name, "size", len(f.Data))
+			documents, err := decoding.Decode(f.Data)
+			if err != nil {
+				slog.Error("failed to decode CustomResourceDefinitions", "file", f.Name, "error", err)
+			}
+			var objects []*unstructured.Unstructured
+			for _, doc := range documents {
+				objects = append(objects, doc.Object)
+			}
+			if err := v.AddCRDs(objects); err != nil {
+				return nil, fmt.Errorf("failed to read CustomResourceDefinitions from %s: %w", f.Name, err)
+			}
 		}
-
-		b.Merge(parsed)
 	}
+	return v, nil
+}
 
-	if len(b.GetConstraintTemplates()) == 0 {
-		return nil, errors.New("no templates were found")
-	}
+// flagRedactPatternsName is the name of the flag of newFlagRedactPatterns.
+const flagRedactPatternsName = "redact-pattern"
 
-	if len(b.GetConstraints()) == 0 {
-		return nil, errors.New("no constraints were found")
+// newFlagRedactPatterns returns the --redact-pattern flag. Its value collects
+// the patterns it is given, so every command needs a flag of its own.
+func newFlagRedactPatterns() *cli.GenericFlag {
+	return &cli.GenericFlag{
+		Name:  flagRedactPatternsName,
+		Usage: "A regular expression to mask in reports and logs. Unlike other flags, its value isn't split on commas",
+		Value: &repeatedValue{},
 	}
-
-	return b, nil
 }
 
-type FileLoader struct {
-	mux fsimpl.FSMux
-}
+// repeatedValue collects the values of a repeated flag, without splitting them
+// on commas like StringSliceFlag.
+type repeatedValue []string
 
-func NewFileLoader() *FileLoader {
-	l := &FileLoader{}
-	mux := fsimpl.NewMux()
-	mux.Add(filefs.FS)
-	mux.Add(httpfs.FS)
-	mux.Add(gitfs.FS)
-	l.mux = mux
-	return l
+func (v *repeatedValue) Set(value string) error {
+	*v = append(*v, value)
+	return nil
 }
 
-func (f *FileLoader) IsSupportedURL(s string) bool {
-	_, err := f.mux.Lookup(s)
-	return err == nil
+func (v *repeatedValue) Get() any {
+	return []string(*v)
 }
 
-func (f *FileLoader) ReadFile(s string) ([]byte, error) {
-
-	base, err := url.Parse(s)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse url: %w", err)
-	}
-
-	// File-systems support only directories, not file as targets.
-	filename := filepath.Base(base.Path)
-	base.Path = strings.TrimSuffix(base.Path, filename)
+func (v *repeatedValue) String() string {
+	return strings.Join(*v, " ")
+}
 
-	target := base.String()
-	slog.Info("Opening", "target", target, "filename", filename)
-	fsys, err := f.mux.Lookup(target)
-	if err != nil {
-		return nil, fmt.Errorf("failed to lookup: %w", err)
+// loadRedactor returns the redactor configured by --redact-path and
+// --redact-pattern, or nil with --no-redact.
+func loadRedactor(cmd *cli.Command) (*reporting.Redactor, error) {
+	if cmd.Bool(flagNoRedact.Name) {
+		return nil, nil
 	}
+	patterns, _ := cmd.Value(flagRedactPatternsName).([]string)
+	return reporting.NewRedactor(cmd.StringSlice(flagRedactPaths.Name), patterns)
+}
 
-	buf, err := fs.ReadFile(fsys, filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+// policyParser returns the function parsing policies, depending on --strict.
+func policyParser(cmd *cli.Command) func([]byte) (*bundle.Bundle, error) {
+	if cmd.Bool(flagStrict.Name) {
+		return bundle.ParsePoliciesStrict
 	}
-	return buf, nil
+	return bundle.ParsePolicies
 }
 
-var loader = NewFileLoader()
-
-func readSource(s string) ([]byte, error) {
-	if s == "" {
-		return nil, errors.New("empty source")
+// prefixErrors returns every error joined in err, prefixed with name.
+func prefixErrors(name string, err error) []error {
+	if err == nil {
+		return nil
 	}
-
-	if loader.IsSupportedURL(s) {
-		return loader.ReadFile(s)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{fmt.Errorf("%s: %w", name, err)}
+	}
+	var out []error
+	for _, err := range joined.Unwrap() {
+		out = append(out, prefixErrors(name, err)...)
 	}
+	return out
+}
 
-	slog.Info("URL is not supported", "url", s)
-	fi, err := os.Stat(s)
-	if err != nil {
-		return nil, err
+// rejectDocuments logs every document rejected in strict mode, masking the
+// values redactor finds sensitive, and fails if there is any. redactor may be
+// nil.
+func rejectDocuments(rejected []error, redactor *reporting.Redactor) error {
+	for _, err := range rejected {
+		slog.Error("rejected document", "error", redactor.RedactMessage(err.Error(), nil))
 	}
-	if fi.IsDir() {
-		return nil, errors.New("argument must point to a file, not a directory")
+	if len(rejected) > 0 {
+		return fmt.Errorf("%d documents were rejected in strict mode", len(rejected))
 	}
-	return os.ReadFile(s)
+	return nil
 }
 
 func readFromStdin() ([]byte, error) {
@@ -5,21 +5,73 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"fmt"
 
-	"github.com/limoges/gatepeeker/internal/bundle"
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/loading"
+	"github.com/limoges/gatepeeker/internal/reporting"
 	"github.com/limoges/gatepeeker/internal/validating"
 	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
 )
 
 func ValidateCmd() *cli.Command {
 	cmd := &cli.Command{}
 	cmd.Name = "validate"
 	cmd.Usage = "Validate manifests against a policy bundle"
+	cmd.ArgsUsage = "[file|directory|glob|url...]"
+	cmd.Description = `
+Manifests are read from stdin and from every argument. An argument may point
+to a file, a directory, which is searched recursively for .yaml, .yml and .json
+files, or a glob pattern, like "deploy/**/*.yaml". Quote glob patterns to keep
+the shell from expanding them.
+
+Directories holding a kustomization.yaml are rendered with kustomize, and their
+output is validated instead of the files they contain. Use --kustomize to
+render a kustomization directly.
+
+Use --helm-chart to render a local chart, as "helm template" does, without
+needing helm to be installed. With --values-matrix, the chart is rendered once
+per values file matching each pattern, which may use **, and violations are
+reported per values set:
+  gatepeeker validate --policies policies/ \
+      --helm-chart charts/platform \
+      --values charts/platform/values.yaml \
+      --values-matrix 'charts/platform/values-*.yaml'
+
+Paths listed in a .gatepeekerignore file at the root of a searched directory
+are skipped. It follows the .gitignore syntax:
+  # skip vendored charts and examples, except one
+  charts/
+  *.example.yaml
+  !deployment.example.yaml
+`
 	cmd.Action = validate
 	cmd.Flags = []cli.Flag{
 		flagPolicies,
+		flagPoliciesInclude,
+		flagPoliciesExclude,
+		flagKustomize,
+		flagHelmChart,
+		flagValues,
+		flagValuesMatrix,
+		flagStrict,
+		flagApplyDefaults,
+		flagDetectDuplicates,
+		flagValidateSchemas,
+		flagKubernetesVersion,
+		flagSchemaLocation,
+		flagCRDs,
+		flagOutput,
+		flagGroupBy,
+		flagJUnitSuites,
+		flagMarkdownMaxSize,
+		flagNoRedact,
+		flagRedactPaths,
+		newFlagRedactPatterns(),
 		flagVerbose,
 	}
 	return cmd
@@ -28,35 +80,56 @@ func ValidateCmd() *cli.Command {
 func validate(ctx context.Context, cmd *cli.Command) error {
 	logging(ctx, cmd)
 
-	b := bundle.New()
+	b, err := loadBundle(cmd)
+	if err != nil {
+		return err
+	}
 
-	urlstrs := cmd.StringSlice(flagPolicies.Name)
-	for _, urlstr := range urlstrs {
+	outputs, err := parseOutputs(cmd, cmd.StringSlice(flagOutput.Name))
+	if err != nil {
+		return err
+	}
 
-		buf, err := readSource(urlstr)
-		if err != nil {
-			return fmt.Errorf("failed to read arg source: %w", err)
-		}
+	redactor, err := loadRedactor(cmd)
+	if err != nil {
+		return err
+	}
 
-		argBundle, err := bundle.ParsePolicies(buf)
-		if err != nil {
-			return fmt.Errorf("failed to build bundle from yaml: %w", err)
-		}
-		b.Merge(argBundle)
+	schemas, err := loadSchemas(cmd)
+	if err != nil {
+		return err
 	}
 
-	client, err := validating.NewClientWithBundle(ctx, b)
+	client, err := validating.NewClientWithBundle(ctx, b,
+		validating.Strict(cmd.Bool(flagStrict.Name)),
+		validating.ApplyDefaults(cmd.Bool(flagApplyDefaults.Name)),
+		validating.DetectDuplicates(cmd.Bool(flagDetectDuplicates.Name)),
+		validating.Schemas(schemas),
+		validating.Redact(redactor),
+	)
 	if err != nil {
 		return err
 	}
 
 	var (
-		failures int
-		output   = os.Stdout
+		report   = reporting.New()
+		rejected []error
+		// unread counts the inputs which couldn't be read, rendered or
+		// validated, which fail the command once the reports are written.
+		unread int
 
-		inputs [][]byte
+		inputs []*loading.File
 	)
 
+	for _, t := range b.GetConstraintTemplates() {
+		template := &reporting.Template{}
+		template.Name = t.GetName()
+		template.Kind = t.Spec.CRD.Spec.Names.Kind
+		template.Title = t.Title()
+		template.Description = t.Description()
+		report.AddTemplate(template)
+	}
+
 	// Read resources to validate from stdin
 	stdin, err := readFromStdin()
 	if err != nil {
@@ -64,35 +137,185 @@ func validate(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	if len(stdin) > 0 {
-		inputs = append(inputs, stdin)
+		input := &loading.File{}
+		input.Name = "stdin"
+		input.Data = stdin
+		inputs = append(inputs, input)
 	}
 
-	for _, arg := range cmd.Args().Slice() {
-		buf, err := readSource(arg)
+	l := loading.New()
+	l.RenderKustomizations = true
+
+	for _, dir := range cmd.StringSlice(flagKustomize.Name) {
+		rendered, err := l.Kustomize(dir)
 		if err != nil {
-			slog.Error("failed to read source", "source", arg, "error", err)
+			slog.Error("failed to render kustomization", "source", dir, "error", err)
+			report.AddError(reporting.Source{File: dir}, err)
+			unread++
 			continue
 		}
-		inputs = append(inputs, buf)
+		inputs = append(inputs, rendered)
 	}
 
-	if len(inputs) == 0 {
-		return errors.New("no files were validated")
+	for _, path := range cmd.StringSlice(flagHelmChart.Name) {
+		chart := &loading.HelmChart{}
+		chart.Path = path
+		chart.Values = cmd.StringSlice(flagValues.Name)
+		chart.Matrix = cmd.StringSlice(flagValuesMatrix.Name)
+		rendered, err := chart.Render(ctx)
+		if err != nil {
+			slog.Error("failed to render chart", "source", path, "error", err)
+			report.AddError(reporting.Source{File: path}, err)
+			unread++
+			continue
+		}
+		inputs = append(inputs, rendered...)
+	}
+
+	for _, arg := range cmd.Args().Slice() {
+		files, err := l.Load(arg)
+		if renderErrs := loading.RenderErrors(err); len(renderErrs) > 0 {
+			for _, renderErr := range renderErrs {
+				slog.Error("failed to render kustomization", "source", renderErr.Name, "error", renderErr.Err)
+				report.AddError(reporting.Source{File: renderErr.Name, Rendered: true}, renderErr.Err)
+				unread++
+			}
+		} else if err != nil {
+			slog.Error("failed to read source", "source", arg, "error", err)
+			report.AddError(reporting.Source{File: arg}, err)
+			unread++
+			continue
+		}
+		inputs = append(inputs, files...)
 	}
 
 	for _, input := range inputs {
-		report, err := client.Validate(ctx, input)
+		source := reporting.Source{}
+		source.File = input.Name
+		source.Variant = input.Variant
+		source.Rendered = input.Rendered
+		validated, err := client.Validate(ctx, source, input.Data)
 		if err != nil {
-			slog.Error("failed to validate", "error", err)
+			var docErr *decoding.DocumentError
+			if errors.As(err, &docErr) {
+				rejected = append(rejected, prefixErrors(source.String(), err)...)
+				continue
+			}
+			slog.Error("failed to validate", "source", source.String(), "error", redactor.RedactMessage(err.Error(), nil))
+			report.AddError(source, err)
+			unread++
 			continue
 		}
-		failures += report.FailureCount()
-		report.WriteTo(output)
+		report.Merge(validated)
+	}
+
+	if redactor != nil {
+		redactor.Redact(report)
+	}
+
+	var errs []error
+	for _, o := range outputs {
+		if err := writeReport(report, o.format, o.path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write %s report: %w", o.value, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if err := rejectDocuments(rejected, redactor); err != nil {
+		return err
+	}
+	if unread > 0 {
+		return fmt.Errorf("%d inputs could not be read, rendered or validated", unread)
+	}
+	if len(inputs) == 0 {
+		return errors.New("no files were validated")
 	}
 
-	if failures > 0 {
+	if failures := report.FailureCount(); failures > 0 {
 		slog.Error("validation failed", "failed", failures)
 		os.Exit(2)
 	}
 	return nil
 }
+
+// output is a report to write, as given to --output.
+type output struct {
+	value  string
+	format reporting.Format
+	// path is the file to write the report to, or empty for stdout.
+	path string
+}
+
+// parseOutputs parses the --output values, of which at most one may write to
+// stdout, and no two to the same file.
+func parseOutputs(cmd *cli.Command, values []string) ([]*output, error) {
+	var (
+		outputs []*output
+		paths   = map[string]string{}
+	)
+	for _, value := range values {
+		format, path, err := parseOutput(cmd, value)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := paths[path]; ok {
+			if path == "" {
+				return nil, fmt.Errorf("outputs %s and %s both write to stdout, use format=path to write one of them to a file", other, value)
+			}
+			return nil, fmt.Errorf("outputs %s and %s both write to %s", other, value, path)
+		}
+		paths[path] = value
+		outputs = append(outputs, &output{value: value, format: format, path: path})
+	}
+	return outputs, nil
+}
+
+// parseOutput parses an --output value, format[=path].
+func parseOutput(cmd *cli.Command, value string) (reporting.Format, string, error) {
+	name, path, _ := strings.Cut(value, "=")
+	if name == "template" {
+		// The template is given as template=path.tmpl[=path].
+		tmpl, path, _ := strings.Cut(path, "=")
+		if tmpl == "" {
+			return nil, "", errors.New("missing template, use template=path.tmpl to write a report with a Go template")
+		}
+		text, err := os.ReadFile(tmpl)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read template: %w", err)
+		}
+		format, err := reporting.ParseTemplate(filepath.Base(tmpl), string(text))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse template: %w", err)
+		}
+		return format, path, nil
+	}
+	opts := reporting.DefaultFormatOptions()
+	opts.TextGroupBy = cmd.String(flagGroupBy.Name)
+	opts.TextColor = path == "" && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+	opts.JUnitSuites = cmd.String(flagJUnitSuites.Name)
+	opts.MarkdownMaxSize = int(cmd.Int(flagMarkdownMaxSize.Name))
+	format, err := reporting.FormatByName(name, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return format, path, nil
+}
+
+// writeReport writes the report to path, or to stdout when path is empty.
+func writeReport(report *reporting.Report, format reporting.Format, path string) error {
+	if path == "" {
+		return format(os.Stdout, report)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := format(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,307 @@
+// Package decoding reads Kubernetes objects from multi-document YAML and JSON
+// streams.
+package decoding
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/yaml"
+)
+
+// helmSourcePrefix starts the comment helm writes before each rendered
+// document, naming the chart template it was rendered from.
+const helmSourcePrefix = "# Source: "
+
+// Document is a single object decoded from a stream.
+type Document struct {
+	Object *unstructured.Unstructured
+	// Raw holds the document as found in the stream, comments included, so
+	// that it can be written back unchanged. Items of a List are marshalled
+	// again, since they don't have a document of their own.
+	Raw []byte
+	// Index is the position of the document in the stream, starting at 0.
+	// Items of a List share the index of the List.
+	Index int
+	// Item is the position of the object in the items of its List, starting
+	// at 1. It is 0 for objects which aren't items of a List.
+	Item int
+	// Line and Column locate the start of the object in the stream,
+	// starting at 1.
+	Line   int
+	Column int
+	// Template is the chart template the document was rendered from, as
+	// found in the "# Source:" comment written by helm.
+	Template string
+}
+
+// DocumentError is the error of a document which could not be decoded.
+type DocumentError struct {
+	Index int
+	Line  int
+	Err   error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document %d at line %d: %v", e.Index, e.Line, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// Errors returns every *DocumentError joined in err.
+func Errors(err error) []*DocumentError {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var out []*DocumentError
+		for _, err := range joined.Unwrap() {
+			out = append(out, Errors(err)...)
+		}
+		return out
+	}
+	var docErr *DocumentError
+	if errors.As(err, &docErr) {
+		return []*DocumentError{docErr}
+	}
+	return nil
+}
+
+// Decode decodes every document of buf. Documents holding only comments or
+// whitespace are skipped, and the items of a List, or of a typed list such as
+// a DeploymentList, are returned as documents of their own.
+//
+// Documents which can't be decoded don't stop decoding: the returned error
+// joins a *DocumentError for each of them, and the other documents are
+// returned along with it.
+func Decode(buf []byte) ([]*Document, error) {
+	var (
+		docs []*Document
+		errs []error
+	)
+	for _, c := range split(buf) {
+		decoded, err := c.decode()
+		if err != nil {
+			errs = append(errs, &DocumentError{Index: c.index, Line: c.line, Err: err})
+			continue
+		}
+		docs = append(docs, decoded...)
+	}
+	return docs, errors.Join(errs...)
+}
+
+// chunk is the raw content of a document found by split.
+type chunk struct {
+	raw      []byte
+	index    int
+	template string
+	// start is the line of raw in the stream.
+	start int
+	// line is the first line holding content, other than comments.
+	line int
+	// offset is the number of columns removed from the first line of raw,
+	// when content follows the document start marker.
+	offset int
+}
+
+// split splits buf on document markers. The YAML specification forbids a
+// line starting with a marker inside any scalar, so splitting on lines is
+// enough to find every document of the stream.
+func split(buf []byte) []*chunk {
+	var (
+		chunks  []*chunk
+		current = &chunk{start: 1}
+		empty   = true
+		lineNo  int
+	)
+	flush := func(next int) {
+		if !empty {
+			current.index = len(chunks)
+			chunks = append(chunks, current)
+		}
+		current = &chunk{start: next}
+		empty = true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(buf)+1)
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		if marker, rest, ok := cutMarker(line); ok {
+			flush(lineNo + 1)
+			if marker == "..." || rest == "" || strings.HasPrefix(rest, "#") {
+				continue
+			}
+			// Content may follow the start marker, as in "--- {}".
+			current.start = lineNo
+			current.offset = len(line) - len(rest)
+			line = rest
+		} else if empty && strings.HasPrefix(line, "%") {
+			// Directives, like %YAML 1.2, only matter to the parser.
+			current.start = lineNo + 1
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, helmSourcePrefix):
+			if current.template == "" {
+				current.template = strings.TrimSpace(strings.TrimPrefix(trimmed, helmSourcePrefix))
+			}
+		case trimmed != "" && !strings.HasPrefix(trimmed, "#"):
+			if empty {
+				current.line = lineNo
+			}
+			empty = false
+		}
+		current.raw = append(current.raw, line...)
+		current.raw = append(current.raw, '\n')
+	}
+	flush(lineNo + 1)
+	return chunks
+}
+
+// cutMarker reports whether line starts with a document start or end marker,
+// and returns what follows the marker.
+func cutMarker(line string) (marker, rest string, ok bool) {
+	for _, marker := range []string{"---", "..."} {
+		if line == marker {
+			return marker, "", true
+		}
+		if strings.HasPrefix(line, marker+" ") || strings.HasPrefix(line, marker+"\t") {
+			return marker, strings.TrimLeft(line[len(marker):], " \t"), true
+		}
+	}
+	return "", "", false
+}
+
+func (c *chunk) decode() ([]*Document, error) {
+	var node yamlv3.Node
+	if err := yamlv3.Unmarshal(c.raw, &node); err != nil {
+		return nil, err
+	}
+	if node.Kind != yamlv3.DocumentNode || len(node.Content) == 0 {
+		return nil, nil
+	}
+	content := node.Content[0]
+	if content.Kind == yamlv3.ScalarNode && content.Tag == "!!null" {
+		return nil, nil
+	}
+	if content.Kind != yamlv3.MappingNode {
+		return nil, fmt.Errorf("expected an object, found %s", content.ShortTag())
+	}
+
+	obj, err := toObject(c.raw)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	doc.Object = obj
+	doc.Raw = c.raw
+	doc.Index = c.index
+	doc.Line, doc.Column = c.position(content)
+	doc.Template = c.template
+
+	if !isList(obj) {
+		return []*Document{doc}, nil
+	}
+	return c.items(doc, content)
+}
+
+// position returns the location of node in the stream.
+func (c *chunk) position(node *yamlv3.Node) (line, column int) {
+	column = node.Column
+	if node.Line == 1 {
+		column += c.offset
+	}
+	return c.start + node.Line - 1, column
+}
+
+// toObject converts a YAML or JSON document to an object. Numbers are
+// converted to int64 or float64, as expected by unstructured.
+func toObject(raw []byte) (*unstructured.Unstructured, error) {
+	buf, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := utiljson.Unmarshal(buf, &obj); err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// isList reports whether obj is a List, as written by kubectl get, or a typed
+// list such as a DeploymentList, as returned by the API server.
+func isList(obj *unstructured.Unstructured) bool {
+	if !strings.HasSuffix(obj.GetKind(), "List") {
+		return false
+	}
+	_, ok := obj.Object["items"].([]interface{})
+	return ok
+}
+
+// items returns the items of a List as documents, located using the node of
+// the List. Items of a typed list usually lack an apiVersion and kind, which
+// are then taken from the list itself.
+func (c *chunk) items(list *Document, node *yamlv3.Node) ([]*Document, error) {
+	items := list.Object.Object["items"].([]interface{})
+	nodes := mappingValue(node, "items")
+
+	apiVersion := list.Object.GetAPIVersion()
+	kind := strings.TrimSuffix(list.Object.GetKind(), "List")
+
+	var docs []*Document
+	for i, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item %d of %s is not an object", i, list.Object.GetKind())
+		}
+		if kind != "" {
+			if _, ok := obj["apiVersion"]; !ok {
+				obj["apiVersion"] = apiVersion
+			}
+			if _, ok := obj["kind"]; !ok {
+				obj["kind"] = kind
+			}
+		}
+		raw, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		doc := &Document{}
+		doc.Object = &unstructured.Unstructured{Object: obj}
+		doc.Raw = raw
+		doc.Index = list.Index
+		doc.Item = i + 1
+		doc.Line, doc.Column = list.Line, list.Column
+		doc.Template = list.Template
+		if nodes != nil && i < len(nodes.Content) {
+			doc.Line, doc.Column = c.position(nodes.Content[i])
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
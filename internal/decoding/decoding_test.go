@@ -0,0 +1,138 @@
+package decoding_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDecode(t *testing.T) {
+	input := `# leading comment only
+---
+# Source: kafka-proxy/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: scripts
+data:
+  run.sh: |
+    echo "--- not a separator"
+    ---not a separator either
+  message: "a --- b"
+---
+---
+  {"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "json"}}
+...
+--- {"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "inline"}}
+`
+	docs, err := decoding.Decode([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+
+	assert.Equal(t, "ConfigMap", docs[0].Object.GetKind())
+	assert.Equal(t, 0, docs[0].Index)
+	assert.Equal(t, 4, docs[0].Line)
+	assert.Equal(t, 1, docs[0].Column)
+	assert.Equal(t, "kafka-proxy/templates/configmap.yaml", docs[0].Template)
+	script, _, _ := unstructured.NestedString(docs[0].Object.Object, "data", "run.sh")
+	assert.Equal(t, "echo \"--- not a separator\"\n---not a separator either\n", script)
+	assert.Contains(t, string(docs[0].Raw), "# Source: kafka-proxy/templates/configmap.yaml")
+
+	assert.Equal(t, "json", docs[1].Object.GetName())
+	assert.Equal(t, 1, docs[1].Index)
+	assert.Equal(t, 15, docs[1].Line)
+	assert.Equal(t, 3, docs[1].Column)
+
+	assert.Equal(t, "inline", docs[2].Object.GetName())
+	assert.Equal(t, 2, docs[2].Index)
+	assert.Equal(t, 17, docs[2].Line)
+	assert.Equal(t, 5, docs[2].Column)
+}
+
+func TestDecodeList(t *testing.T) {
+	input := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: first
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: second
+`
+	docs, err := decoding.Decode([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	assert.Equal(t, "first", docs[0].Object.GetName())
+	assert.Equal(t, 4, docs[0].Line)
+	assert.Equal(t, 3, docs[0].Column)
+	assert.Equal(t, 1, docs[0].Item)
+	assert.Equal(t, "second", docs[1].Object.GetName())
+	assert.Equal(t, 8, docs[1].Line)
+	assert.Equal(t, 2, docs[1].Item)
+	assert.YAMLEq(t, "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: second\n", string(docs[1].Raw))
+}
+
+func TestDecodeTypedList(t *testing.T) {
+	input := `{
+  "apiVersion": "apps/v1",
+  "kind": "DeploymentList",
+  "metadata": {"resourceVersion": "42"},
+  "items": [
+    {"metadata": {"name": "web", "namespace": "default"}},
+    {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "api"}}
+  ]
+}
+`
+	docs, err := decoding.Decode([]byte(input))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	assert.Equal(t, "apps/v1", docs[0].Object.GetAPIVersion())
+	assert.Equal(t, "Deployment", docs[0].Object.GetKind())
+	assert.Equal(t, "web", docs[0].Object.GetName())
+	assert.Equal(t, 6, docs[0].Line)
+	assert.Equal(t, "Deployment", docs[1].Object.GetKind())
+	assert.Equal(t, "api", docs[1].Object.GetName())
+	assert.Equal(t, 7, docs[1].Line)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	input := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: valid
+---
+# comment
+apiVersion: v1
+kind: Namespace
+metadata: [
+---
+- not
+- an object
+`
+	docs, err := decoding.Decode([]byte(input))
+	require.Len(t, docs, 1)
+	assert.Equal(t, "valid", docs[0].Object.GetName())
+
+	var docErr *decoding.DocumentError
+	require.ErrorAs(t, err, &docErr)
+	assert.Equal(t, 1, docErr.Index)
+	assert.Equal(t, 7, docErr.Line)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok)
+	errs := joined.Unwrap()
+	require.Len(t, errs, 2)
+	require.True(t, errors.As(errs[1], &docErr))
+	assert.Equal(t, 2, docErr.Index)
+	assert.Equal(t, 11, docErr.Line)
+	assert.ErrorContains(t, errs[1], "expected an object")
+}
@@ -0,0 +1,147 @@
+// Package defaulting applies the defaults the Kubernetes API server sets on
+// common built-in types, so that resources are reviewed as the admission
+// webhook of a cluster would see them.
+//
+// The defaulting funcs are the generated ones of k8s.io/kubernetes, with the
+// feature gates at their default values. Only the core, apps, batch and
+// networking groups are covered; other resources are left untouched.
+package defaulting
+
+import (
+	"fmt"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	appsdefaults "k8s.io/kubernetes/pkg/apis/apps/v1"
+	batchdefaults "k8s.io/kubernetes/pkg/apis/batch/v1"
+	coredefaults "k8s.io/kubernetes/pkg/apis/core/v1"
+	networkingdefaults "k8s.io/kubernetes/pkg/apis/networking/v1"
+)
+
+// newScheme returns the scheme holding the types and defaulting funcs of the
+// covered groups. It is built once, on first use.
+var newScheme = sync.OnceValues(func() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{
+		corev1.AddToScheme,
+		appsv1.AddToScheme,
+		batchv1.AddToScheme,
+		networkingv1.AddToScheme,
+		coredefaults.RegisterDefaults,
+		appsdefaults.RegisterDefaults,
+		batchdefaults.RegisterDefaults,
+		networkingdefaults.RegisterDefaults,
+	} {
+		if err := add(scheme); err != nil {
+			return nil, err
+		}
+	}
+	return scheme, nil
+})
+
+// Default returns a copy of obj with the API server defaults applied. Fields
+// already set in obj are kept as is, and unknown fields are preserved. obj is
+// returned unchanged when its type isn't known.
+func Default(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	scheme, err := newScheme()
+	if err != nil {
+		return nil, fmt.Errorf("failed to register the defaulting funcs: %w", err)
+	}
+
+	gvk := obj.GroupVersionKind()
+	if !scheme.Recognizes(gvk) {
+		return obj, nil
+	}
+
+	typed, err := scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed); err != nil {
+		return nil, fmt.Errorf("failed to convert %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+	scheme.Default(typed)
+
+	defaulted, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s %s: %w", gvk.Kind, obj.GetName(), err)
+	}
+
+	out := obj.DeepCopy()
+	mergeMissing(out.Object, defaulted)
+	return out, nil
+}
+
+// mergeMissing adds to dst the fields of src it lacks. Converting a typed
+// object back also yields empty values, like a null creationTimestamp or an
+// empty status, which aren't defaults and are skipped.
+func mergeMissing(dst, src map[string]interface{}) {
+	for k, v := range src {
+		existing, ok := dst[k]
+		if !ok {
+			if !isEmpty(v) {
+				dst[k] = prune(v)
+			}
+			continue
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if existing, ok := existing.(map[string]interface{}); ok {
+				mergeMissing(existing, v)
+			}
+		case []interface{}:
+			existing, ok := existing.([]interface{})
+			if !ok || len(existing) != len(v) {
+				continue
+			}
+			for i := range v {
+				d, dok := existing[i].(map[string]interface{})
+				s, sok := v[i].(map[string]interface{})
+				if dok && sok {
+					mergeMissing(d, s)
+				}
+			}
+		}
+	}
+}
+
+// prune removes the empty fields of v.
+func prune(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if isEmpty(e) {
+				delete(v, k)
+				continue
+			}
+			v[k] = prune(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = prune(e)
+		}
+	}
+	return v
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, e := range v {
+			if !isEmpty(e) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
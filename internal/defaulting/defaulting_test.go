@@ -0,0 +1,114 @@
+package defaulting_test
+
+import (
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/defaulting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func decode(t *testing.T, manifest string) *unstructured.Unstructured {
+	t.Helper()
+	docs, err := decoding.Decode([]byte(manifest))
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	return docs[0].Object
+}
+
+func TestDefaultDeployment(t *testing.T) {
+	obj := decode(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  annotations:
+    example.com/kept: "true"
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        ports:
+        - containerPort: 80
+        unknownField: kept
+      - name: sidecar
+        image: busybox:1.36
+        imagePullPolicy: Always
+`)
+
+	out, err := defaulting.Default(obj)
+	require.NoError(t, err)
+
+	replicas, _, _ := unstructured.NestedInt64(out.Object, "spec", "replicas")
+	assert.Equal(t, int64(3), replicas)
+	strategy, _, _ := unstructured.NestedString(out.Object, "spec", "strategy", "type")
+	assert.Equal(t, "RollingUpdate", strategy)
+	restartPolicy, _, _ := unstructured.NestedString(out.Object, "spec", "template", "spec", "restartPolicy")
+	assert.Equal(t, "Always", restartPolicy)
+
+	containers, _, _ := unstructured.NestedSlice(out.Object, "spec", "template", "spec", "containers")
+	require.Len(t, containers, 2)
+	web := containers[0].(map[string]interface{})
+	assert.Equal(t, "Always", web["imagePullPolicy"])
+	assert.Equal(t, "kept", web["unknownField"])
+	port := web["ports"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "TCP", port["protocol"])
+	sidecar := containers[1].(map[string]interface{})
+	assert.Equal(t, "Always", sidecar["imagePullPolicy"])
+
+	assert.Equal(t, "true", out.GetAnnotations()["example.com/kept"])
+	_, found, _ := unstructured.NestedFieldNoCopy(out.Object, "metadata", "creationTimestamp")
+	assert.False(t, found)
+	_, found, _ = unstructured.NestedFieldNoCopy(out.Object, "status")
+	assert.False(t, found)
+
+	// The input is left untouched.
+	_, found, _ = unstructured.NestedFieldNoCopy(obj.Object, "spec", "strategy")
+	assert.False(t, found)
+}
+
+func TestDefaultImagePullPolicy(t *testing.T) {
+	for image, policy := range map[string]string{
+		"nginx":                          "Always",
+		"nginx:latest":                   "Always",
+		"nginx:1.27":                     "IfNotPresent",
+		"registry.local:5000/nginx":      "Always",
+		"registry.local:5000/nginx:1.27": "IfNotPresent",
+		"nginx@sha256:0123":              "IfNotPresent",
+	} {
+		obj := decode(t, `apiVersion: v1
+kind: Pod
+metadata:
+  name: pod
+spec:
+  containers:
+  - name: main
+    image: `+image+"\n")
+
+		out, err := defaulting.Default(obj)
+		require.NoError(t, err)
+		containers, _, _ := unstructured.NestedSlice(out.Object, "spec", "containers")
+		assert.Equal(t, policy, containers[0].(map[string]interface{})["imagePullPolicy"], image)
+	}
+}
+
+func TestDefaultUnknownKind(t *testing.T) {
+	obj := decode(t, `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+`)
+	out, err := defaulting.Default(obj)
+	require.NoError(t, err)
+	assert.Same(t, obj, out)
+}
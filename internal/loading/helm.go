@@ -0,0 +1,164 @@
+package loading
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli/values"
+)
+
+// HelmChart describes how to render a local chart, like `helm template` does.
+//
+// The chart is rendered once per combination of the values files found in
+// Matrix. Each entry of Matrix is a glob pattern, and each file it matches is
+// an alternative for that dimension of the matrix. Values files are layered
+// in order: Values first, then one file from each dimension.
+type HelmChart struct {
+	Path   string
+	Values []string
+	Matrix []string
+}
+
+// Render renders the chart once per values set. The Variant of each file
+// lists the values files picked from the matrix, or Values when there is no
+// matrix.
+func (c *HelmChart) Render(ctx context.Context) ([]*File, error) {
+	sets, err := c.valuesSets()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*File
+	for _, set := range sets {
+		values := append(append([]string{}, c.Values...), set...)
+		buf, err := c.template(ctx, values)
+		if err != nil {
+			return nil, err
+		}
+
+		f := &File{}
+		f.Name = filepath.ToSlash(filepath.Clean(c.Path))
+		f.Variant = strings.Join(set, ", ")
+		if len(c.Matrix) == 0 {
+			f.Variant = strings.Join(c.Values, ", ")
+		}
+		f.Rendered = true
+		f.Data = buf
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// valuesSets expands the matrix into every combination of values files.
+func (c *HelmChart) valuesSets() ([][]string, error) {
+	sets := [][]string{nil}
+	for _, pattern := range c.Matrix {
+		matches, err := globFiles(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid values matrix pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no values files match %q", pattern)
+		}
+
+		var expanded [][]string
+		for _, set := range sets {
+			for _, match := range matches {
+				next := append(append([]string{}, set...), match)
+				expanded = append(expanded, next)
+			}
+		}
+		sets = expanded
+	}
+	return sets, nil
+}
+
+// globFiles returns the local files matching pattern, which supports "**"
+// like the sources of a Loader.
+func globFiles(pattern string) ([]string, error) {
+	dir, glob := splitGlob(filepath.ToSlash(pattern))
+	if glob == "" {
+		if _, err := os.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{dir}, nil
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, err
+	}
+
+	root := dir
+	if root == "" {
+		root = "."
+	}
+	var matches []string
+	err := fs.WalkDir(os.DirFS(root), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && matchGlob(glob, p) {
+			matches = append(matches, path.Join(dir, p))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func (c *HelmChart) template(ctx context.Context, valueFiles []string) ([]byte, error) {
+	chart, err := loader.Load(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart %s: %w", c.Path, err)
+	}
+	if deps := chart.Metadata.Dependencies; deps != nil {
+		if err := action.CheckDependencies(chart, deps); err != nil {
+			return nil, fmt.Errorf("failed to load chart %s: %w", c.Path, err)
+		}
+	}
+
+	opts := &values.Options{}
+	opts.ValueFiles = valueFiles
+	vals, err := opts.MergeValues(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values %v: %w", valueFiles, err)
+	}
+
+	cfg := &action.Configuration{}
+	cfg.Log = func(format string, v ...interface{}) {
+		slog.Debug(fmt.Sprintf(format, v...), "chart", c.Path)
+	}
+	install := action.NewInstall(cfg)
+	install.DryRun = true
+	install.DryRunOption = "true"
+	install.ClientOnly = true
+	install.Replace = true
+	// The release name must be a valid DNS name, which the directory of the
+	// chart may not be, so the default name of `helm template` is used.
+	install.ReleaseName = "release-name"
+	install.Namespace = "default"
+
+	slog.Info("Rendering chart", "chart", c.Path, "values", valueFiles)
+	rel, err := install.RunWithContext(ctx, chart, vals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart %s with values %v: %w", c.Path, valueFiles, err)
+	}
+
+	// Hooks are written after the manifest, as `helm template` does.
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, strings.TrimSpace(rel.Manifest))
+	for _, hook := range rel.Hooks {
+		fmt.Fprintf(&buf, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package loading
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"strings"
+)
+
+// IgnoreFile is the name of the file listing paths to skip when walking a
+// directory. It follows a subset of the .gitignore syntax.
+const IgnoreFile = ".gatepeekerignore"
+
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreList holds the rules of an ignore file, in the order they appear.
+type ignoreList []ignoreRule
+
+func parseIgnore(buf []byte) ignoreList {
+	var rules ignoreList
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Match reports whether the slash-separated path rel, relative to the
+// directory holding the ignore file, is ignored. The last matching rule wins.
+func (l ignoreList) Match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range l {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.matches(rel) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (r ignoreRule) matches(rel string) bool {
+	if r.anchored {
+		return matchGlob(r.pattern, rel)
+	}
+	return matchGlob(r.pattern, path.Base(rel))
+}
@@ -0,0 +1,101 @@
+package loading
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// Kustomize renders the kustomization at source, which must point to a
+// directory. The rendered file is named after the directory.
+func (l *Loader) Kustomize(source string) (*File, error) {
+	u, display, err := parseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fsys, err := l.mux.New(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup: %w", err)
+	}
+	if !isKustomization(fsys, ".") {
+		return nil, errors.New("no kustomization file found")
+	}
+
+	buf, err := kustomize(u.Scheme, u.Path, fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+	f.Name = fileName(u, display, ".")
+	f.Rendered = true
+	f.Data = buf
+	return f, nil
+}
+
+// isKustomization reports whether dir holds a kustomization file.
+func isKustomization(fsys fs.FS, dir string) bool {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if fi, err := fs.Stat(fsys, path.Join(dir, name)); err == nil && !fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// kustomize renders the kustomization at dir, relative to the root of fsys.
+// Local kustomizations are rendered from disk so that they may refer to
+// directories outside of root. Others are copied in memory first, and may
+// only refer to files found under root.
+func kustomize(scheme, rootPath string, fsys fs.FS, dir string) ([]byte, error) {
+	var (
+		kfs    filesys.FileSystem
+		target string
+	)
+	if scheme == "file" {
+		kfs = filesys.MakeFsOnDisk()
+		target = filepath.FromSlash(path.Join(rootPath, dir))
+	} else {
+		kfs = filesys.MakeFsInMemory()
+		if err := copyFS(kfs, fsys); err != nil {
+			return nil, fmt.Errorf("failed to copy kustomization: %w", err)
+		}
+		target = path.Join("/", dir)
+	}
+
+	slog.Info("Rendering kustomization", "target", target)
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	m, err := k.Run(kfs, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render kustomization: %w", err)
+	}
+	return m.AsYaml()
+}
+
+func copyFS(dst filesys.FileSystem, src fs.FS) error {
+	return fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := path.Join("/", p)
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return dst.MkdirAll(target)
+		}
+		buf, err := fs.ReadFile(src, p)
+		if err != nil {
+			return err
+		}
+		return dst.WriteFile(target, buf)
+	})
+}
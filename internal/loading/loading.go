@@ -0,0 +1,307 @@
+package loading
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/blobfs"
+	"github.com/hairyhenderson/go-fsimpl/filefs"
+	"github.com/hairyhenderson/go-fsimpl/gitfs"
+	"github.com/hairyhenderson/go-fsimpl/httpfs"
+)
+
+// File is the content of a single file read from a source.
+type File struct {
+	// Name is where the file was read from, as shown to users.
+	Name string
+	// Variant distinguishes the renders of the same source, like the values
+	// files a chart was rendered with.
+	Variant string
+	// Rendered is set when Data is the output of kustomize or helm, so that
+	// lines of Data don't locate anything in Name, which is the directory of
+	// the kustomization or the root of the chart.
+	Rendered bool
+	Data     []byte
+}
+
+// RenderError is the error of a kustomization which could not be rendered
+// while walking a directory.
+type RenderError struct {
+	// Name is the directory of the kustomization, named like File.Name.
+	Name string
+	Err  error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("failed to render %s: %v", e.Name, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// RenderErrors returns every *RenderError joined in err.
+func RenderErrors(err error) []*RenderError {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var out []*RenderError
+		for _, err := range joined.Unwrap() {
+			out = append(out, RenderErrors(err)...)
+		}
+		return out
+	}
+	var renderErr *RenderError
+	if errors.As(err, &renderErr) {
+		return []*RenderError{renderErr}
+	}
+	return nil
+}
+
+// Loader reads files from local paths and from the URLs supported by fsimpl.
+//
+// A source may point to a single file, to a directory which is walked
+// recursively, or to a glob pattern such as "manifests/**/*.yaml". HTTP
+// sources can't be listed, so they must point to a single file.
+type Loader struct {
+	// Include lists the glob patterns a file found while walking a directory
+	// must match to be read. When empty, files with a .yaml, .yml or .json
+	// extension are read.
+	Include []string
+	// Exclude lists the glob patterns of files and directories to skip while
+	// walking a directory.
+	Exclude []string
+	// RenderKustomizations renders the directories holding a kustomization
+	// file found while walking, instead of reading their files.
+	RenderKustomizations bool
+
+	mux        fsimpl.FSMux
+	extensions []string
+}
+
+func New() *Loader {
+	l := &Loader{}
+	mux := fsimpl.NewMux()
+	mux.Add(filefs.FS)
+	mux.Add(httpfs.FS)
+	mux.Add(blobfs.FS)
+	mux.Add(gitfs.FS)
+	l.mux = mux
+	l.extensions = []string{".yaml", ".yml", ".json"}
+	return l
+}
+
+// Load returns every file found at source.
+//
+// Kustomizations which can't be rendered while walking a directory don't stop
+// the walk: the returned error joins a *RenderError for each of them, and the
+// other files are returned along with it.
+func (l *Loader) Load(source string) ([]*File, error) {
+	if source == "" {
+		return nil, errors.New("empty source")
+	}
+
+	u, display, err := parseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, pattern := splitGlob(u.Path)
+	if pattern != "" {
+		if isHTTP(u) {
+			return nil, fmt.Errorf("glob patterns are not supported for http sources, since they can't be listed: %s", source)
+		}
+		root := *u
+		root.Path = dir
+		if display != "" {
+			display, _ = splitGlob(display)
+			if display == "" {
+				display = "."
+			}
+		}
+		return l.walk(&root, display, pattern)
+	}
+
+	if !isHTTP(u) {
+		fsys, err := l.mux.New(u)
+		if err == nil {
+			if fi, err := fs.Stat(fsys, "."); err == nil && fi.IsDir() {
+				return l.walk(u, display, "")
+			}
+		}
+	}
+	return l.readFile(u, display)
+}
+
+// parseSource converts source to a URL. Local paths are converted to
+// absolute file URLs, and display holds the path as given by the user so
+// that files are named relative to it. display is empty for URLs.
+func parseSource(source string) (u *url.URL, display string, err error) {
+	u, err = url.Parse(source)
+	if err == nil && len(u.Scheme) > 1 {
+		return refToFragment(u), "", nil
+	}
+
+	// Not a URL (or a Windows drive letter): treat as a local path.
+	abs, err := filepath.Abs(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to convert to absolute path: %w", err)
+	}
+	u = &url.URL{}
+	u.Scheme = "file"
+	u.Path = filepath.ToSlash(abs)
+	return u, filepath.ToSlash(filepath.Clean(source)), nil
+}
+
+// refToFragment supports the "?ref=" query used by kustomize and go-getter
+// to select a git branch or tag, which gitfs expects as the URL fragment.
+func refToFragment(u *url.URL) *url.URL {
+	if !strings.HasPrefix(u.Scheme, "git") {
+		return u
+	}
+	query := u.Query()
+	ref := query.Get("ref")
+	if ref == "" || u.Fragment != "" {
+		return u
+	}
+	query.Del("ref")
+	out := *u
+	out.RawQuery = query.Encode()
+	out.Fragment = ref
+	return &out
+}
+
+func isHTTP(u *url.URL) bool {
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+func (l *Loader) readFile(u *url.URL, display string) ([]*File, error) {
+	// File-systems support only directories, not file as targets.
+	dir := *u
+	filename := path.Base(u.Path)
+	dir.Path = strings.TrimSuffix(u.Path, filename)
+
+	slog.Info("Opening", "target", dir.String(), "filename", filename)
+	fsys, err := l.mux.New(&dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup: %w", err)
+	}
+
+	buf, err := fs.ReadFile(fsys, filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	f := &File{}
+	f.Name = display
+	if f.Name == "" {
+		f.Name = u.String()
+	}
+	f.Data = buf
+	return []*File{f}, nil
+}
+
+// walk reads the files found under root. Files must match pattern when it is
+// set, and the include patterns of the loader when they are set. Otherwise,
+// only files with a known extension are kept.
+func (l *Loader) walk(root *url.URL, display, pattern string) ([]*File, error) {
+	slog.Info("Walking", "target", root.String(), "pattern", pattern)
+	fsys, err := l.mux.New(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup: %w", err)
+	}
+
+	var ignored ignoreList
+	if buf, err := fs.ReadFile(fsys, IgnoreFile); err == nil {
+		ignored = parseIgnore(buf)
+	}
+
+	var (
+		files      []*File
+		renderErrs []error
+	)
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != "." && (ignored.Match(p, d.IsDir()) || matchAny(l.Exclude, p, d.IsDir())) {
+			slog.Info("Ignoring", "path", p)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			if pattern == "" && l.RenderKustomizations && isKustomization(fsys, p) {
+				buf, err := kustomize(root.Scheme, root.Path, fsys, p)
+				if err != nil {
+					// A broken kustomization doesn't stop the others from
+					// being rendered.
+					renderErrs = append(renderErrs, &RenderError{Name: fileName(root, display, p), Err: err})
+					return fs.SkipDir
+				}
+				f := &File{}
+				f.Name = fileName(root, display, p)
+				f.Rendered = true
+				f.Data = buf
+				files = append(files, f)
+				return fs.SkipDir
+			}
+			return nil
+		}
+		switch {
+		case pattern != "" && !matchGlob(pattern, p):
+			return nil
+		case len(l.Include) > 0 && !matchAny(l.Include, p, false):
+			return nil
+		case pattern == "" && len(l.Include) == 0 && !slices.Contains(l.extensions, strings.ToLower(path.Ext(p))):
+			return nil
+		}
+
+		buf, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		f := &File{}
+		f.Name = fileName(root, display, p)
+		f.Data = buf
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root.String(), err)
+	}
+	return files, errors.Join(renderErrs...)
+}
+
+// fileName names the file at rel, relative to root. Paths are joined without
+// cleaning, which would collapse the "//" separator used by git URLs.
+func fileName(root *url.URL, display, rel string) string {
+	if rel == "." {
+		if display != "" {
+			return display
+		}
+		return root.String()
+	}
+	if display == "." {
+		return rel
+	}
+	if display != "" {
+		return strings.TrimSuffix(display, "/") + "/" + rel
+	}
+	u := *root
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + rel
+	return u.String()
+}
@@ -0,0 +1,243 @@
+package loading_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/loading"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+	return dir
+}
+
+func names(files []*loading.File) (out []string) {
+	for _, f := range files {
+		out = append(out, f.Name)
+	}
+	return out
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"deployment.yaml": "kind: Deployment",
+	})
+	t.Chdir(dir)
+
+	files, err := loading.New().Load("deployment.yaml")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "deployment.yaml", files[0].Name)
+	assert.Equal(t, "kind: Deployment", string(files[0].Data))
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"manifests/app/deployment.yaml":  "",
+		"manifests/app/service.yml":      "",
+		"manifests/app/README.md":        "",
+		"manifests/list.json":            "",
+		"manifests/charts/chart.yaml":    "",
+		"manifests/secret.example.yaml":  "",
+		"manifests/keep.example.yaml":    "",
+		"manifests/.git/config.yaml":     "",
+		"manifests/.gatepeekerignore":    "# comment\ncharts/\n*.example.yaml\n!keep.example.yaml\n",
+		"outside/should-not-appear.yaml": "",
+	})
+	t.Chdir(dir)
+
+	files, err := loading.New().Load("manifests")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"manifests/app/deployment.yaml",
+		"manifests/app/service.yml",
+		"manifests/keep.example.yaml",
+		"manifests/list.json",
+	}, names(files))
+}
+
+func TestLoadGlob(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"deploy/a.yaml":          "",
+		"deploy/nested/b.yaml":   "",
+		"deploy/nested/c.json":   "",
+		"deploy/nested/d/e.yaml": "",
+	})
+	t.Chdir(dir)
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"deploy/*.yaml", []string{"deploy/a.yaml"}},
+		{"deploy/**/*.yaml", []string{"deploy/a.yaml", "deploy/nested/b.yaml", "deploy/nested/d/e.yaml"}},
+		{"deploy/*/*.json", []string{"deploy/nested/c.json"}},
+		{"*/nested/*.yaml", []string{"deploy/nested/b.yaml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			files, err := loading.New().Load(tt.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(files))
+		})
+	}
+	_, err := loading.New().Load("https://example.com/deploy/*.yaml")
+	assert.ErrorContains(t, err, "glob patterns are not supported for http sources")
+}
+
+func TestLoadIncludeExclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"policies/allowedrepos/template.yaml":   "",
+		"policies/allowedrepos/constraint.yaml": "",
+		"policies/allowedrepos/samples/a.yaml":  "",
+		"policies/labels/template.yaml":         "",
+		"policies/labels/constraint.yaml":       "",
+		"policies/labels/kustomization.yaml":    "",
+	})
+	t.Chdir(dir)
+
+	l := loading.New()
+	l.Include = []string{"template.yaml", "constraint.yaml"}
+	l.Exclude = []string{"samples/", "labels/constraint.yaml"}
+
+	files, err := l.Load("policies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"policies/allowedrepos/constraint.yaml",
+		"policies/allowedrepos/template.yaml",
+		"policies/labels/template.yaml",
+	}, names(files))
+}
+
+func TestLoadExcludeDirectoryOnly(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"manifests/samples/a.yaml": "",
+		"manifests/other/samples":  "",
+	})
+	t.Chdir(dir)
+
+	l := loading.New()
+	l.Include = []string{"*.yaml", "samples"}
+	l.Exclude = []string{"samples/"}
+
+	files, err := l.Load("manifests")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"manifests/other/samples"}, names(files))
+}
+
+func TestLoadKustomization(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base/kustomization.yaml":          "resources:\n- configmap.yaml\n",
+		"base/configmap.yaml":              "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n",
+		"overlays/prod/kustomization.yaml": "resources:\n- ../../base\nnamePrefix: prod-\n",
+		"overlays/prod/unused.yaml":        "kind: ShouldNotBeRead\n",
+	})
+	t.Chdir(dir)
+
+	l := loading.New()
+	l.RenderKustomizations = true
+
+	files, err := l.Load("overlays")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "overlays/prod", files[0].Name)
+	assert.True(t, files[0].Rendered)
+	assert.YAMLEq(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prod-app\n", string(files[0].Data))
+
+	// A broken overlay is reported, and doesn't stop the others from being
+	// rendered.
+	broken := writeFiles(t, map[string]string{
+		"overlays/broken/kustomization.yaml": "resources:\n- missing.yaml\n",
+		"overlays/prod/kustomization.yaml":   "resources:\n- configmap.yaml\n",
+		"overlays/prod/configmap.yaml":       "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: prod\n",
+		"overlays/namespace.yaml":            "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n",
+	})
+	files, err = l.Load(filepath.Join(broken, "overlays"))
+	renderErrs := loading.RenderErrors(err)
+	require.Len(t, renderErrs, 1, err)
+	assert.Equal(t, filepath.Join(broken, "overlays", "broken"), renderErrs[0].Name)
+	assert.Equal(t, []string{
+		filepath.Join(broken, "overlays", "namespace.yaml"),
+		filepath.Join(broken, "overlays", "prod"),
+	}, names(files))
+
+	f, err := l.Kustomize("base")
+	require.NoError(t, err)
+	assert.Equal(t, "base", f.Name)
+	assert.True(t, f.Rendered)
+	assert.YAMLEq(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n", string(f.Data))
+}
+
+func TestHelmChartMatrix(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"chart/Chart.yaml":             "apiVersion: v2\nname: web\nversion: 0.1.0\n",
+		"chart/values.yaml":            "env: none\nregion: none\n",
+		"chart/templates/env.yaml":     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  env: {{ .Values.env }}\n  region: {{ .Values.region }}\n",
+		"values/base.yaml":             "region: unset\n",
+		"values/values-dev.yaml":       "env: dev\n",
+		"values/values-prod.yaml":      "env: prod\n",
+		"values/region/eu/values.yaml": "region: eu\n",
+		"values/region/us/values.yaml": "region: us\n",
+		"values/region/README.md":      "",
+	})
+	t.Chdir(dir)
+
+	chart := &loading.HelmChart{}
+	chart.Path = "chart"
+	chart.Values = []string{"values/base.yaml"}
+	chart.Matrix = []string{"values/values-*.yaml", "values/region/**/*.yaml"}
+
+	files, err := chart.Render(t.Context())
+	require.NoError(t, err)
+
+	var variants, rendered []string
+	for _, f := range files {
+		assert.Equal(t, "chart", f.Name)
+		assert.True(t, f.Rendered)
+		variants = append(variants, f.Variant)
+		rendered = append(rendered, string(f.Data))
+	}
+	assert.Equal(t, []string{
+		"values/values-dev.yaml, values/region/eu/values.yaml",
+		"values/values-dev.yaml, values/region/us/values.yaml",
+		"values/values-prod.yaml, values/region/eu/values.yaml",
+		"values/values-prod.yaml, values/region/us/values.yaml",
+	}, variants)
+	assert.Equal(t, "---\n# Source: web/templates/env.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: release-name\ndata:\n  env: dev\n  region: eu\n", rendered[0])
+
+	chart.Matrix = []string{"values/missing-*.yaml"}
+	_, err = chart.Render(t.Context())
+	assert.ErrorContains(t, err, `no values files match "values/missing-*.yaml"`)
+}
+
+func TestHelmChartCurrentDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"My_Chart/Chart.yaml":          "apiVersion: v2\nname: web\nversion: 0.1.0\n",
+		"My_Chart/templates/name.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n",
+	})
+
+	for _, tt := range []struct{ dir, path string }{
+		{filepath.Join(dir, "My_Chart"), "."},
+		{dir, "My_Chart"},
+	} {
+		t.Chdir(tt.dir)
+		chart := &loading.HelmChart{}
+		chart.Path = tt.path
+
+		files, err := chart.Render(t.Context())
+		require.NoError(t, err, tt.path)
+		require.Len(t, files, 1)
+		assert.Equal(t, tt.path, files[0].Name)
+		assert.Contains(t, string(files[0].Data), "name: release-name\n")
+	}
+}
@@ -0,0 +1,84 @@
+package loading
+
+import (
+	"path"
+	"strings"
+)
+
+// hasMeta reports whether s contains any glob meta characters.
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[`)
+}
+
+// splitGlob splits p into a directory without glob meta characters and the
+// remaining pattern. The pattern is empty when p contains no meta characters.
+func splitGlob(p string) (dir, pattern string) {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if hasMeta(segment) {
+			return strings.Join(segments[:i], "/"), strings.Join(segments[i:], "/")
+		}
+	}
+	return p, ""
+}
+
+// matchGlob reports whether the slash-separated name matches pattern. Besides
+// the syntax supported by path.Match, a "**" segment matches zero or more
+// directories.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive "**" and try every possible split.
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// matchAny reports whether the slash-separated name matches any of patterns.
+// Like in ignore files, a pattern without a slash is matched against the last
+// element of name only, and a pattern ending with a slash only matches
+// directories.
+func matchAny(patterns []string, name string, isDir bool) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+		if !strings.Contains(pattern, "/") {
+			if matchGlob(pattern, path.Base(name)) {
+				return true
+			}
+			continue
+		}
+		if matchGlob(strings.TrimPrefix(pattern, "/"), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,337 @@
+// Package openapi validates resources against the OpenAPI schemas of the
+// built-in Kubernetes types and of CustomResourceDefinitions, catching the
+// mistakes the API server would reject before admission, like unknown fields
+// or values of the wrong type.
+package openapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/limoges/gatepeeker/internal/loading"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	// DefaultVersion is the Kubernetes version whose schemas are used by
+	// default, matching the version of k8s.io/api. Its documents are embedded.
+	DefaultVersion = "1.32.3"
+	// DefaultLocation is where the OpenAPI v3 documents of the built-in types
+	// are read from, for versions other than DefaultVersion. {version} is
+	// replaced by the Kubernetes version.
+	DefaultLocation = "https://raw.githubusercontent.com/kubernetes/kubernetes/{version}/api/openapi-spec/v3"
+)
+
+// embedded holds the gzipped OpenAPI v3 documents of DefaultVersion, as
+// published in api/openapi-spec/v3 of the Kubernetes repository.
+//
+//go:embed schemas
+var embedded embed.FS
+
+const (
+	extensionGroupVersionKind     = "x-kubernetes-group-version-kind"
+	extensionPreserveUnknownField = "x-kubernetes-preserve-unknown-fields"
+	extensionIntOrString          = "x-kubernetes-int-or-string"
+)
+
+// FieldError is a field of a resource which doesn't match its schema.
+type FieldError struct {
+	// Path is the field in dotted notation, like spec.containers[0].name.
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validator validates resources against the schemas of their kind. The
+// documents of the built-in types are read when a resource of their group is
+// first validated.
+//
+// A Validator is not safe for concurrent use.
+type Validator struct {
+	// Documents are read from fsys when set, or from location.
+	fsys     fs.FS
+	location string
+	loader   *loading.Loader
+
+	// schemas holds the components of every document read, by name, to
+	// resolve references.
+	schemas map[string]*spec.Schema
+	kinds   map[schema.GroupVersionKind]*spec.Schema
+	// loaded holds the groups whose document was read, with the error of
+	// reading it, if any.
+	loaded map[schema.GroupVersion]error
+}
+
+// NewValidator returns a Validator for the given Kubernetes version, like
+// 1.32 or v1.32.3, reading the documents of the built-in types from location.
+// When location is empty, the embedded documents are used for DefaultVersion,
+// and DefaultLocation for other versions.
+func NewValidator(location, version string) (*Validator, error) {
+	v := &Validator{}
+	v.loader = loading.New()
+	v.schemas = map[string]*spec.Schema{}
+	v.kinds = map[schema.GroupVersionKind]*spec.Schema{}
+	v.loaded = map[schema.GroupVersion]error{}
+
+	version = normalizeVersion(version)
+	if location == "" && version == normalizeVersion(DefaultVersion) {
+		fsys, err := fs.Sub(embedded, "schemas/"+version)
+		if err != nil {
+			return nil, err
+		}
+		v.fsys = fsys
+		return v, nil
+	}
+	if location == "" {
+		location = DefaultLocation
+	}
+	v.location = strings.TrimSuffix(strings.ReplaceAll(location, "{version}", version), "/")
+
+	// A local directory is checked right away, since it costs nothing, so
+	// that a mistyped location isn't only reported when a group is read.
+	if !strings.Contains(v.location, "://") {
+		if _, err := os.Stat(v.location); err != nil {
+			return nil, fmt.Errorf("failed to read the schemas of Kubernetes %s: %w", version, err)
+		}
+	}
+	return v, nil
+}
+
+// normalizeVersion returns version as a release tag, like v1.32.0.
+func normalizeVersion(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	if strings.Count(version, ".") == 1 {
+		version += ".0"
+	}
+	return "v" + version
+}
+
+// document is the part of an OpenAPI v3 document holding the schemas.
+type document struct {
+	Components struct {
+		Schemas map[string]*spec.Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// documentName returns the name of the document of gv, as published in the
+// Kubernetes repository.
+func documentName(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return fmt.Sprintf("api__%s_openapi.json", gv.Version)
+	}
+	return fmt.Sprintf("apis__%s__%s_openapi.json", gv.Group, gv.Version)
+}
+
+func (v *Validator) load(gv schema.GroupVersion) error {
+	source, data, err := v.read(documentName(gv))
+	if err != nil {
+		return err
+	}
+
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+	for name, s := range doc.Components.Schemas {
+		v.schemas[name] = s
+		for _, gvk := range groupVersionKinds(s) {
+			v.kinds[gvk] = s
+		}
+	}
+	return nil
+}
+
+// read returns the document named name, and where it was read from.
+func (v *Validator) read(name string) (string, []byte, error) {
+	if v.fsys != nil {
+		source := "embedded " + name
+		f, err := v.fsys.Open(name + ".gz")
+		if err != nil {
+			return source, nil, err
+		}
+		defer f.Close()
+		r, err := gzip.NewReader(f)
+		if err != nil {
+			return source, nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, r); err != nil {
+			return source, nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		return source, buf.Bytes(), nil
+	}
+
+	source := v.location + "/" + name
+	files, err := v.loader.Load(source)
+	if err != nil {
+		return source, nil, err
+	}
+	if len(files) == 0 {
+		return source, nil, fmt.Errorf("no document found at %s", source)
+	}
+	return source, files[0].Data, nil
+}
+
+func groupVersionKinds(s *spec.Schema) (out []schema.GroupVersionKind) {
+	var gvks []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	}
+	if err := s.Extensions.GetObject(extensionGroupVersionKind, &gvks); err != nil {
+		return nil
+	}
+	for _, gvk := range gvks {
+		out = append(out, schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind})
+	}
+	return out
+}
+
+// AddCRDs registers the schemas of the CustomResourceDefinitions found in
+// objects. Other objects are ignored.
+func (v *Validator) AddCRDs(objects []*unstructured.Unstructured) error {
+	var errs []error
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if gvk.Group != "apiextensions.k8s.io" || gvk.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		if gvk.Version != "v1" {
+			slog.Warn("skipping CustomResourceDefinition, only apiextensions.k8s.io/v1 is supported", "name", obj.GetName(), "version", gvk.Version)
+			continue
+		}
+		if err := v.addCRD(obj); err != nil {
+			errs = append(errs, fmt.Errorf("invalid CustomResourceDefinition %s: %w", obj.GetName(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (v *Validator) addCRD(obj *unstructured.Unstructured) error {
+	group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+	versions, _, err := unstructured.NestedSlice(obj.Object, "spec", "versions")
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		version, ok := version.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(version, "name")
+		raw, found, err := unstructured.NestedMap(version, "schema", "openAPIV3Schema")
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+
+		buf, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		s := &spec.Schema{}
+		if err := json.Unmarshal(buf, s); err != nil {
+			return fmt.Errorf("version %s: %w", name, err)
+		}
+		allowObjectMeta(s)
+		v.kinds[schema.GroupVersionKind{Group: group, Version: name, Kind: kind}] = s
+	}
+	return nil
+}
+
+// allowObjectMeta adds the fields the API server validates on its own to the
+// root of a CRD schema, where they are usually left out.
+func allowObjectMeta(s *spec.Schema) {
+	if len(s.Properties) == 0 {
+		return
+	}
+	for _, name := range []string{"apiVersion", "kind", "metadata"} {
+		if _, ok := s.Properties[name]; !ok {
+			s.Properties[name] = spec.Schema{}
+		}
+	}
+}
+
+// Validate returns the fields of obj which don't match the schema of its kind.
+// Resources of a kind without a schema aren't validated. An error is returned
+// when the document of its group exists but can't be read, so that resources
+// aren't silently left unchecked.
+func (v *Validator) Validate(obj *unstructured.Unstructured) ([]*FieldError, error) {
+	s, err := v.schemaFor(obj.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		slog.Debug("no schema found, skipping schema validation", "gvk", obj.GroupVersionKind().String(), "name", obj.GetName())
+		return nil, nil
+	}
+
+	w := &walker{validator: v}
+	w.validate("", obj.Object, s)
+	return w.errs, nil
+}
+
+func (v *Validator) schemaFor(gvk schema.GroupVersionKind) (*spec.Schema, error) {
+	if s, ok := v.kinds[gvk]; ok {
+		return s, nil
+	}
+	gv := gvk.GroupVersion()
+	err, ok := v.loaded[gv]
+	if !ok {
+		err = v.load(gv)
+		if isNotFound(err) {
+			// Custom resources have no document, which is expected.
+			slog.Debug("no built-in schemas", "groupVersion", gv.String(), "error", err)
+			err = nil
+		}
+		v.loaded[gv] = err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the schemas of %s: %w", gv.String(), err)
+	}
+	return v.kinds[gvk], nil
+}
+
+// isNotFound reports whether err tells that a document doesn't exist, rather
+// than that it couldn't be read.
+func isNotFound(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	var httpErr interface{ StatusCode() int }
+	return errors.As(err, &httpErr) && httpErr.StatusCode() == http.StatusNotFound
+}
+
+// resolve follows the reference of s, if any.
+func (v *Validator) resolve(s *spec.Schema) *spec.Schema {
+	for s != nil {
+		ref := s.Ref.String()
+		if ref == "" {
+			return s
+		}
+		s = v.schemas[ref[strings.LastIndex(ref, "/")+1:]]
+	}
+	return nil
+}
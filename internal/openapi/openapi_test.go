@@ -0,0 +1,221 @@
+package openapi_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func decode(t *testing.T, manifests string) []*unstructured.Unstructured {
+	t.Helper()
+	docs, err := decoding.Decode([]byte(manifests))
+	require.NoError(t, err)
+	var objects []*unstructured.Unstructured
+	for _, doc := range docs {
+		objects = append(objects, doc.Object)
+	}
+	return objects
+}
+
+func validate(t *testing.T, v *openapi.Validator, obj *unstructured.Unstructured) (out []string) {
+	t.Helper()
+	errs, err := v.Validate(obj)
+	require.NoError(t, err)
+	for _, err := range errs {
+		out = append(out, err.Error())
+	}
+	return out
+}
+
+func TestValidateBuiltin(t *testing.T) {
+	v, err := openapi.NewValidator("testdata", openapi.DefaultVersion)
+	require.NoError(t, err)
+
+	objects := decode(t, `apiVersion: v1
+kind: Pod
+metadata:
+  name: valid
+  creationTimestamp: null
+  labels:
+    app: web
+spec:
+  containers:
+  - name: web
+    image: nginx
+    imagePullPolicy: IfNotPresent
+    ports:
+    - containerPort: 80
+    resources:
+      limits:
+        cpu: 1
+        memory: 128Mi
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: invalid
+  labels:
+    replicas: 3
+spec:
+  hostNetwork: "true"
+  containers:
+  - image: nginx
+    imagePullPolcy: Always
+    imagePullPolicy: Sometimes
+    ports:
+    - containerPort: http
+    resources:
+      limits:
+        cpu: [1]
+`)
+	require.Len(t, objects, 2)
+
+	assert.Empty(t, validate(t, v, objects[0]))
+	assert.Equal(t, []string{
+		`metadata.labels.replicas: expected a string, found 3`,
+		`spec.containers[0].name: required field is missing`,
+		`spec.containers[0].imagePullPolcy: unknown field`,
+		`spec.containers[0].imagePullPolicy: unsupported value "Sometimes", expected one of "Always", "IfNotPresent", "Never"`,
+		`spec.containers[0].ports[0].containerPort: expected an integer, found "http"`,
+		`spec.containers[0].resources.limits.cpu: invalid value an array, doesn't match any of the allowed types`,
+		`spec.hostNetwork: expected a boolean, found "true"`,
+	}, validate(t, v, objects[1]))
+}
+
+func TestValidateEmbedded(t *testing.T) {
+	v, err := openapi.NewValidator("", openapi.DefaultVersion)
+	require.NoError(t, err)
+
+	objects := decode(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: three
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`)
+	require.Len(t, objects, 1)
+
+	assert.Equal(t, []string{
+		`spec.replicas: expected an integer, found "three"`,
+	}, validate(t, v, objects[0]))
+}
+
+func TestValidateCRD(t *testing.T) {
+	v, err := openapi.NewValidator("testdata", openapi.DefaultVersion)
+	require.NoError(t, err)
+
+	crds := decode(t, `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            required: [size]
+            properties:
+              size:
+                type: integer
+              extra:
+                type: object
+                x-kubernetes-preserve-unknown-fields: true
+`)
+	require.NoError(t, v.AddCRDs(crds))
+
+	objects := decode(t, `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: valid
+spec:
+  size: 3
+  extra:
+    anything: goes
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: invalid
+spec:
+  colour: blue
+---
+apiVersion: example.com/v2
+kind: Widget
+metadata:
+  name: unknown-version
+spec:
+  colour: blue
+`)
+	require.Len(t, objects, 3)
+
+	assert.Empty(t, validate(t, v, objects[0]))
+	assert.Equal(t, []string{
+		"spec.size: required field is missing",
+		"spec.colour: unknown field",
+	}, validate(t, v, objects[1]))
+	assert.Empty(t, validate(t, v, objects[2]))
+}
+
+func TestNewValidatorInvalidLocation(t *testing.T) {
+	_, err := openapi.NewValidator("testdata/missing", openapi.DefaultVersion)
+	assert.Error(t, err)
+}
+
+func TestValidateUnreadableDocument(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apis__apps__v1_openapi.json"), []byte("{"), 0o644))
+	v, err := openapi.NewValidator(dir, openapi.DefaultVersion)
+	require.NoError(t, err)
+
+	objects := decode(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+`)
+	require.Len(t, objects, 3)
+
+	// Every resource of the group is reported, not only the first one.
+	_, err = v.Validate(objects[0])
+	assert.ErrorContains(t, err, "failed to read the schemas of apps/v1")
+	_, err = v.Validate(objects[1])
+	assert.ErrorContains(t, err, "failed to read the schemas of apps/v1")
+
+	// Groups without a document are skipped.
+	errs, err := v.Validate(objects[2])
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+}
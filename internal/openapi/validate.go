@@ -0,0 +1,192 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// walker validates a value against a schema, following the rules of the API
+// server rather than the whole of the OpenAPI specification: fields set to
+// null are ignored, and objects with known properties reject unknown fields,
+// as with strict field validation.
+type walker struct {
+	validator *Validator
+	errs      []*FieldError
+}
+
+func (w *walker) fail(path, format string, args ...interface{}) {
+	w.errs = append(w.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (w *walker) validate(path string, value interface{}, s *spec.Schema) {
+	s = w.validator.resolve(s)
+	if s == nil || value == nil {
+		return
+	}
+
+	for i := range s.AllOf {
+		w.validate(path, value, &s.AllOf[i])
+	}
+	if alternatives := append(append([]spec.Schema{}, s.OneOf...), s.AnyOf...); len(alternatives) > 0 {
+		if !w.matchesAny(path, value, alternatives) {
+			w.fail(path, "invalid value %s, doesn't match any of the allowed types", describe(value))
+			return
+		}
+	}
+
+	if isIntOrString(s) {
+		if !isInteger(value) && !isString(value) {
+			w.fail(path, "expected an integer or a string, found %s", describe(value))
+		}
+		return
+	}
+
+	switch schemaType(s) {
+	case "object":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			w.fail(path, "expected an object, found %s", describe(value))
+			return
+		}
+		w.object(path, m, s)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			w.fail(path, "expected an array, found %s", describe(value))
+			return
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			for i, item := range items {
+				w.validate(fmt.Sprintf("%s[%d]", path, i), item, s.Items.Schema)
+			}
+		}
+	case "string":
+		if !isString(value) {
+			w.fail(path, "expected a string, found %s", describe(value))
+			return
+		}
+	case "integer":
+		if !isInteger(value) {
+			w.fail(path, "expected an integer, found %s", describe(value))
+			return
+		}
+	case "number":
+		switch value.(type) {
+		case int64, float64:
+		default:
+			w.fail(path, "expected a number, found %s", describe(value))
+			return
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			w.fail(path, "expected a boolean, found %s", describe(value))
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e interface{}) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		w.fail(path, "unsupported value %s, expected one of %s", describe(value), describeEnum(s.Enum))
+	}
+}
+
+func (w *walker) object(path string, m map[string]interface{}, s *spec.Schema) {
+	for _, name := range s.Required {
+		if _, ok := m[name]; !ok {
+			w.fail(join(path, name), "required field is missing")
+		}
+	}
+
+	preserveUnknown, _ := s.Extensions.GetBool(extensionPreserveUnknownField)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		if property, ok := s.Properties[k]; ok {
+			w.validate(join(path, k), m[k], &property)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+			w.validate(join(path, k), m[k], s.AdditionalProperties.Schema)
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Allows:
+		case len(s.Properties) > 0 && !preserveUnknown:
+			w.fail(join(path, k), "unknown field")
+		}
+	}
+}
+
+// matchesAny reports whether value is valid against one of the schemas.
+func (w *walker) matchesAny(path string, value interface{}, schemas []spec.Schema) bool {
+	for i := range schemas {
+		alternative := &walker{validator: w.validator}
+		alternative.validate(path, value, &schemas[i])
+		if len(alternative.errs) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaType(s *spec.Schema) string {
+	if len(s.Type) > 0 {
+		return s.Type[0]
+	}
+	if len(s.Properties) > 0 || s.AdditionalProperties != nil {
+		return "object"
+	}
+	return ""
+}
+
+func isIntOrString(s *spec.Schema) bool {
+	intOrString, _ := s.Extensions.GetBool(extensionIntOrString)
+	return intOrString || s.Format == "int-or-string"
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func isInteger(value interface{}) bool {
+	switch v := value.(type) {
+	case int64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	}
+	return false
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+	return fmt.Sprint(value)
+}
+
+func describeEnum(values []interface{}) string {
+	var out []string
+	for _, v := range values {
+		out = append(out, describe(v))
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,141 @@
+package reporting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteGitHub writes the report as GitHub Actions workflow commands, which
+// annotate the files of a pull request. Denials and errors are errors,
+// warnings are warnings, and other enforcement actions are notices.
+func WriteGitHub(w io.Writer, r *Report) error {
+	for _, result := range r.results {
+		for _, v := range result.Violations {
+			command := "notice"
+			switch v.EnforcementAction {
+			case "deny":
+				command = "error"
+			case "warn":
+				command = "warning"
+			}
+			title := violationName(v)
+			msg := withDetails(fmt.Sprintf("%s: %s", ResourceName(result.Object), v.Message), v)
+			if err := writeGitHubCommand(w, command, result.Source, title, msg); err != nil {
+				return err
+			}
+		}
+	}
+	for _, e := range r.errors {
+		if err := writeGitHubCommand(w, "error", e.Source, "gatepeeker", e.Err.Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeGitHubCommand(w io.Writer, command string, source Source, title, msg string) error {
+	file, line, column := source.Location()
+	properties := []string{"file=" + escapeGitHubProperty(file)}
+	if line > 0 {
+		properties = append(properties, fmt.Sprintf("line=%d", line))
+	}
+	if column > 0 {
+		properties = append(properties, fmt.Sprintf("col=%d", column))
+	}
+	properties = append(properties, "title="+escapeGitHubProperty(title))
+	_, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(properties, ","), escapeGitHubData(msg))
+	return err
+}
+
+// escapeGitHubData escapes the message of a workflow command, as done by
+// @actions/core.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+	Content     *codeQualityContent `json:"content,omitempty"`
+}
+
+// codeQualityContent holds the Markdown body of an issue, with its details.
+type codeQualityContent struct {
+	Body string `json:"body"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// WriteGitLabCodeQuality writes the report as a GitLab Code Quality artifact,
+// in the Code Climate format. Denials and errors are major issues, warnings
+// are minor, and other enforcement actions are informational.
+func WriteGitLabCodeQuality(w io.Writer, r *Report) error {
+	issues := []*codeQualityIssue{}
+	for _, result := range r.results {
+		resource := ResourceName(result.Object)
+		for _, v := range result.Violations {
+			severity := "info"
+			switch v.EnforcementAction {
+			case "deny":
+				severity = "major"
+			case "warn":
+				severity = "minor"
+			}
+			check := violationName(v)
+			msg := fmt.Sprintf("%s: %s", resource, v.Message)
+			issue := newCodeQualityIssue(result.Source, check, severity, msg)
+			if details := formatDetails(v.Details, "  "); details != "" {
+				issue.Content = &codeQualityContent{Body: fmt.Sprintf("```json\n%s\n```", details)}
+			}
+			issues = append(issues, issue)
+		}
+	}
+	for _, e := range r.errors {
+		issues = append(issues, newCodeQualityIssue(e.Source, "gatepeeker", "major", e.Err.Error()))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
+
+func newCodeQualityIssue(source Source, check, severity, msg string) *codeQualityIssue {
+	file, line, _ := source.Location()
+	if line == 0 {
+		line = 1
+	}
+
+	issue := &codeQualityIssue{}
+	issue.Description = msg
+	issue.CheckName = check
+	issue.Severity = severity
+	issue.Location.Path = file
+	issue.Location.Lines.Begin = line
+
+	// GitLab tracks issues across pipelines by their fingerprint, which must
+	// not depend on the line, so that moving a resource doesn't make its
+	// issues new. The document and item tell apart the issues of resources
+	// sharing their identity, which GitLab would otherwise merge.
+	position := fmt.Sprintf("%d.%d", source.Document, source.Item)
+	sum := sha256.Sum256([]byte(strings.Join([]string{check, source.Input(), position, source.Template, msg}, "\x00")))
+	issue.Fingerprint = hex.EncodeToString(sum[:])
+	return issue
+}
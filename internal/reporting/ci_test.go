@@ -0,0 +1,102 @@
+package reporting_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func ciReport() *reporting.Report {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+
+	report := reporting.New()
+	result := &reporting.Result{}
+	result.Object = newObject("apps/v1", "Deployment", "default", "web")
+	result.Source = reporting.Source{File: "manifests/web,api.yaml", Line: 12, Column: 1}
+	result.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "owner", EnforcementAction: "deny", Message: "missing owner\nadd an owner label", Details: map[string]interface{}{"missing_labels": []interface{}{"owner"}}},
+		{Constraint: labels, ConstraintName: "team", EnforcementAction: "warn", Message: "100% missing team"},
+	}
+	report.AddResult(result)
+	report.AddError(reporting.Source{File: "broken.yaml", Line: 3}, errors.New("did not find expected key"))
+	return report
+}
+
+func TestWriteGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteGitHub(&buf, ciReport()))
+	assert.Equal(t, ""+
+		"::error file=manifests/web%2Capi.yaml,line=12,col=1,title=K8sRequiredLabels/owner::apps:v1:Deployment:default:web: missing owner%0Aadd an owner label%0A{%0A  \"missing_labels\": [%0A    \"owner\"%0A  ]%0A}\n"+
+		"::warning file=manifests/web%2Capi.yaml,line=12,col=1,title=K8sRequiredLabels/team::apps:v1:Deployment:default:web: 100%25 missing team\n"+
+		"::error file=broken.yaml,line=3,title=gatepeeker::did not find expected key\n",
+		buf.String())
+}
+
+func TestWriteGitLabCodeQuality(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteGitLabCodeQuality(&buf, ciReport()))
+
+	var issues []struct {
+		Description string `json:"description"`
+		CheckName   string `json:"check_name"`
+		Fingerprint string `json:"fingerprint"`
+		Severity    string `json:"severity"`
+		Location    struct {
+			Path  string `json:"path"`
+			Lines struct {
+				Begin int `json:"begin"`
+			} `json:"lines"`
+		} `json:"location"`
+		Content *struct {
+			Body string `json:"body"`
+		} `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &issues))
+	require.Len(t, issues, 3)
+
+	assert.Equal(t, "K8sRequiredLabels/owner", issues[0].CheckName)
+	assert.Equal(t, "major", issues[0].Severity)
+	assert.Equal(t, "manifests/web,api.yaml", issues[0].Location.Path)
+	assert.Equal(t, 12, issues[0].Location.Lines.Begin)
+	require.NotNil(t, issues[0].Content)
+	assert.Equal(t, "```json\n{\n  \"missing_labels\": [\n    \"owner\"\n  ]\n}\n```", issues[0].Content.Body)
+	assert.Equal(t, "minor", issues[1].Severity)
+	assert.Nil(t, issues[1].Content)
+	assert.Equal(t, "gatepeeker", issues[2].CheckName)
+	assert.Equal(t, "major", issues[2].Severity)
+
+	assert.Len(t, issues[0].Fingerprint, 64)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+}
+
+func TestGitLabCodeQualityFingerprints(t *testing.T) {
+	issues := func(line int) (out []struct {
+		Fingerprint string `json:"fingerprint"`
+	}) {
+		report := reporting.New()
+		for item := 1; item <= 2; item++ {
+			result := &reporting.Result{}
+			result.Object = newObject("apps/v1", "Deployment", "default", "web")
+			result.Source = reporting.Source{File: "list.yaml", Item: item, Line: line + item*10}
+			result.Violations = []*reporting.Violation{{EnforcementAction: "deny", Message: "invalid"}}
+			report.AddResult(result)
+		}
+		var buf bytes.Buffer
+		require.NoError(t, reporting.WriteGitLabCodeQuality(&buf, report))
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+		require.Len(t, out, 2)
+		return out
+	}
+
+	// Items of a List sharing their identity get issues of their own, which
+	// don't change when the List moves.
+	before, after := issues(1), issues(5)
+	assert.NotEqual(t, before[0].Fingerprint, before[1].Fingerprint)
+	assert.Equal(t, before, after)
+}
@@ -0,0 +1,152 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// CTRF statuses, see https://ctrf.io/docs/specification/status.
+const (
+	ctrfPassed  = "passed"
+	ctrfFailed  = "failed"
+	ctrfSkipped = "skipped"
+	ctrfOther   = "other"
+)
+
+type ctrfReport struct {
+	ReportFormat string      `json:"reportFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	Results      ctrfResults `json:"results"`
+}
+
+type ctrfResults struct {
+	Tool    ctrfTool    `json:"tool"`
+	Summary ctrfSummary `json:"summary"`
+	Tests   []*ctrfTest `json:"tests"`
+}
+
+type ctrfTool struct {
+	Name string `json:"name"`
+}
+
+type ctrfSummary struct {
+	Tests   int   `json:"tests"`
+	Passed  int   `json:"passed"`
+	Failed  int   `json:"failed"`
+	Pending int   `json:"pending"`
+	Skipped int   `json:"skipped"`
+	Other   int   `json:"other"`
+	Start   int64 `json:"start"`
+	Stop    int64 `json:"stop"`
+}
+
+type ctrfTest struct {
+	Name     string                 `json:"name"`
+	Status   string                 `json:"status"`
+	Duration int64                  `json:"duration"`
+	Message  string                 `json:"message,omitempty"`
+	FilePath string                 `json:"filePath,omitempty"`
+	Line     int                    `json:"line,omitempty"`
+	Suite    string                 `json:"suite,omitempty"`
+	Type     string                 `json:"type"`
+	Extra    map[string]interface{} `json:"extra,omitempty"`
+}
+
+// WriteCTRF writes the report in the Common Test Report Format. Every
+// constraint evaluated against a resource is a test, which fails on a denial.
+// Constraints which don't match the resource are skipped, and the other
+// enforcement actions are reported with the other status.
+//
+// The review of a resource evaluates all constraints at once, so each of its
+// tests is given the duration of the whole review.
+func WriteCTRF(w io.Writer, r *Report) error {
+	out := &ctrfReport{}
+	out.ReportFormat = "CTRF"
+	out.SpecVersion = "0.0.0"
+	out.Results.Tool.Name = "gatepeeker"
+	out.Results.Tests = []*ctrfTest{}
+
+	var start, stop time.Time
+	for _, result := range r.results {
+		if !result.Start.IsZero() && (start.IsZero() || result.Start.Before(start)) {
+			start = result.Start
+		}
+		if end := result.Start.Add(result.Duration); end.After(stop) {
+			stop = end
+		}
+
+		for _, e := range result.Evaluations {
+			test := newCTRFTest(result, e)
+			out.Results.Tests = append(out.Results.Tests, test)
+
+			summary := &out.Results.Summary
+			summary.Tests++
+			switch test.Status {
+			case ctrfPassed:
+				summary.Passed++
+			case ctrfFailed:
+				summary.Failed++
+			case ctrfSkipped:
+				summary.Skipped++
+			default:
+				summary.Other++
+			}
+		}
+	}
+	if !start.IsZero() {
+		out.Results.Summary.Start = start.UnixMilli()
+		out.Results.Summary.Stop = stop.UnixMilli()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func newCTRFTest(result *Result, e *Evaluation) *ctrfTest {
+	resource := ResourceName(result.Object)
+
+	test := &ctrfTest{}
+	test.Name = fmt.Sprintf("%s %s", resource, evaluationName(e))
+	test.Status = ctrfPassed
+	test.Duration = result.Duration.Milliseconds()
+	test.FilePath = result.Source.File
+	test.Line = result.Source.Line
+	test.Suite = result.Source.String()
+	test.Type = "policy"
+	test.Extra = map[string]interface{}{"resource": resource}
+	if e.Template != "" {
+		test.Extra["template"] = e.Template
+	}
+
+	if e.Skipped {
+		test.Status = ctrfSkipped
+		return test
+	}
+
+	var (
+		messages []string
+		details  []map[string]interface{}
+	)
+	for _, v := range result.violations(e) {
+		messages = append(messages, v.Message)
+		if v.Details != nil {
+			details = append(details, v.Details)
+		}
+		switch {
+		case v.EnforcementAction == "deny":
+			test.Status = ctrfFailed
+		case test.Status == ctrfPassed:
+			test.Status = ctrfOther
+		}
+		test.Extra["enforcementAction"] = v.EnforcementAction
+	}
+	test.Message = strings.Join(messages, "\n")
+	if len(details) > 0 {
+		test.Extra["details"] = details
+	}
+	return test
+}
@@ -0,0 +1,85 @@
+package reporting_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWriteCTRF(t *testing.T) {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+	replicas := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sReplicaLimits"}
+	probes := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredProbes"}
+	start := time.UnixMilli(1700000000000)
+
+	result := &reporting.Result{}
+	result.Object = newObject("apps/v1", "Deployment", "default", "web")
+	result.Source = reporting.Source{File: "deploy.yaml", Line: 3, Column: 1}
+	result.Start = start
+	result.Duration = 12 * time.Millisecond
+	result.Evaluations = []*reporting.Evaluation{
+		{Constraint: labels, ConstraintName: "owner", Template: "k8srequiredlabels"},
+		{Constraint: replicas, ConstraintName: "max", Template: "k8sreplicalimits"},
+		{Constraint: probes, ConstraintName: "probes", Template: "k8srequiredprobes"},
+		{Constraint: labels, ConstraintName: "team", Template: "k8srequiredlabels", Skipped: true},
+	}
+	result.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "owner", EnforcementAction: "deny", Message: "missing owner", Details: map[string]interface{}{"missing_labels": []interface{}{"owner"}}},
+		{Constraint: probes, ConstraintName: "probes", EnforcementAction: "warn", Message: "missing probes"},
+	}
+	report := reporting.New()
+	report.AddResult(result)
+
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteCTRF(&buf, report))
+
+	var out struct {
+		ReportFormat string `json:"reportFormat"`
+		Results      struct {
+			Tool    struct{ Name string }
+			Summary map[string]int64
+			Tests   []struct {
+				Name     string
+				Status   string
+				Duration int64
+				Message  string
+				FilePath string
+				Line     int
+				Extra    map[string]interface{}
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+
+	assert.Equal(t, "CTRF", out.ReportFormat)
+	assert.Equal(t, "gatepeeker", out.Results.Tool.Name)
+	assert.Equal(t, map[string]int64{
+		"tests":   4,
+		"passed":  1,
+		"failed":  1,
+		"pending": 0,
+		"skipped": 1,
+		"other":   1,
+		"start":   1700000000000,
+		"stop":    1700000000012,
+	}, out.Results.Summary)
+
+	tests := out.Results.Tests
+	require.Len(t, tests, 4)
+	assert.Equal(t, "apps:v1:Deployment:default:web K8sRequiredLabels/owner", tests[0].Name)
+	assert.Equal(t, "failed", tests[0].Status)
+	assert.Equal(t, "missing owner", tests[0].Message)
+	assert.Equal(t, []interface{}{map[string]interface{}{"missing_labels": []interface{}{"owner"}}}, tests[0].Extra["details"])
+	assert.Equal(t, int64(12), tests[0].Duration)
+	assert.Equal(t, "deploy.yaml", tests[0].FilePath)
+	assert.Equal(t, 3, tests[0].Line)
+	assert.Equal(t, "passed", tests[1].Status)
+	assert.Equal(t, "other", tests[2].Status)
+	assert.Equal(t, "skipped", tests[3].Status)
+}
@@ -0,0 +1,70 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// Format writes a report to w.
+type Format func(w io.Writer, r *Report) error
+
+// FormatOptions holds the settings of the formats which can be configured.
+type FormatOptions struct {
+	// TextGroupBy is how the text format groups results, see TextGroups.
+	TextGroupBy string
+	// TextColor colors the text format with ANSI escape codes.
+	TextColor bool
+	// JUnitSuites is what the JUnit format makes a test suite of.
+	JUnitSuites string
+	// MarkdownMaxSize is the size the markdown format is truncated to, or 0.
+	MarkdownMaxSize int
+}
+
+// DefaultFormatOptions returns the options formats use unless configured.
+func DefaultFormatOptions() *FormatOptions {
+	opts := &FormatOptions{}
+	opts.TextGroupBy = TextGroupByResource
+	opts.JUnitSuites = JUnitSuitesByFile
+	opts.MarkdownMaxSize = MarkdownMaxSize
+	return opts
+}
+
+func fixed(f Format) func(*FormatOptions) (Format, error) {
+	return func(*FormatOptions) (Format, error) {
+		return f, nil
+	}
+}
+
+var formats = map[string]func(*FormatOptions) (Format, error){
+	"text": func(opts *FormatOptions) (Format, error) {
+		return Text(opts.TextGroupBy, opts.TextColor)
+	},
+	"json":               fixed(WriteJSON),
+	"ctrf":               fixed(WriteCTRF),
+	"sarif":              fixed(WriteSARIF),
+	"github":             fixed(WriteGitHub),
+	"gitlab-codequality": fixed(WriteGitLabCodeQuality),
+	"markdown": func(opts *FormatOptions) (Format, error) {
+		return Markdown(opts.MarkdownMaxSize), nil
+	},
+	"html": fixed(WriteHTML),
+	"junit": func(opts *FormatOptions) (Format, error) {
+		return JUnit(opts.JUnitSuites)
+	},
+}
+
+// FormatByName returns the format registered as name, configured by opts.
+func FormatByName(name string, opts *FormatOptions) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q, expected one of %v", name, FormatNames())
+	}
+	return f(opts)
+}
+
+// FormatNames returns the names of the formats, sorted.
+func FormatNames() []string {
+	return slices.Sorted(maps.Keys(formats))
+}
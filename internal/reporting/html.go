@@ -0,0 +1,118 @@
+package reporting
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlReport = template.Must(template.New("report").Parse(htmlTemplate))
+
+type htmlData struct {
+	Summary     htmlSummary
+	Templates   []*Template
+	Results     []*htmlResult
+	Errors      []*InputError
+	Constraints []string
+	Namespaces  []string
+	Kinds       []string
+}
+
+type htmlSummary struct {
+	Resources  int
+	Passed     int
+	Failed     int
+	Violations int
+	Errors     int
+}
+
+type htmlResult struct {
+	Resource  string
+	Kind      string
+	Namespace string
+	Source    string
+	Failed    bool
+	// Constraints lists the violated constraints, separated by spaces, for
+	// filtering.
+	Constraints string
+	Violations  []*htmlViolation
+}
+
+type htmlViolation struct {
+	Constraint        string
+	Template          string
+	EnforcementAction string
+	Message           string
+	Target            string
+	Details           string
+}
+
+// WriteHTML writes the report as a single HTML page, with no external
+// assets, listing the resources with their violations, which can be filtered
+// by constraint, namespace and kind, and the templates they were validated
+// against.
+func WriteHTML(w io.Writer, r *Report) error {
+	data := &htmlData{}
+	data.Templates = r.templates
+	data.Errors = r.errors
+	data.Summary.Resources = len(r.results)
+	data.Summary.Errors = len(r.errors)
+
+	var (
+		constraints = map[string]bool{}
+		namespaces  = map[string]bool{}
+		kinds       = map[string]bool{}
+		templates   = map[string]bool{}
+	)
+	for _, t := range r.templates {
+		templates[t.Name] = true
+	}
+
+	for _, result := range r.results {
+		res := &htmlResult{}
+		res.Resource = ResourceName(result.Object)
+		res.Kind = result.Object.GetKind()
+		res.Namespace = result.Object.GetNamespace()
+		res.Source = result.Source.String()
+		res.Failed = result.FailureCount() > 0
+
+		var names []string
+		for _, v := range result.Violations {
+			violation := &htmlViolation{}
+			violation.Constraint = violationName(v)
+			if templates[v.Template] {
+				violation.Template = v.Template
+			}
+			violation.EnforcementAction = v.EnforcementAction
+			violation.Message = v.Message
+			violation.Target = v.Target
+			violation.Details = formatDetails(v.Details, "  ")
+			res.Violations = append(res.Violations, violation)
+
+			names = append(names, violation.Constraint)
+			constraints[violation.Constraint] = true
+		}
+		res.Constraints = strings.Join(names, " ")
+		namespaces[res.Namespace] = true
+		kinds[res.Kind] = true
+
+		if res.Failed {
+			data.Summary.Failed++
+		} else {
+			data.Summary.Passed++
+		}
+		data.Summary.Violations += len(result.Violations)
+		data.Results = append(data.Results, res)
+	}
+
+	data.Constraints = slices.Sorted(maps.Keys(constraints))
+	data.Namespaces = slices.Sorted(maps.Keys(namespaces))
+	data.Kinds = slices.Sorted(maps.Keys(kinds))
+	return htmlReport.Execute(w, data)
+}
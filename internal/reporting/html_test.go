@@ -0,0 +1,48 @@
+package reporting_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWriteHTML(t *testing.T) {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+
+	report := reporting.New()
+	report.AddTemplate(&reporting.Template{Name: "k8srequiredlabels", Kind: "K8sRequiredLabels", Title: "Required Labels", Description: "Requires <labels>."})
+
+	failed := &reporting.Result{}
+	failed.Object = newObject("apps/v1", "Deployment", "default", "web")
+	failed.Source = reporting.Source{File: "web.yaml", Line: 1, Column: 1}
+	failed.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "owner", Template: "k8srequiredlabels", EnforcementAction: "deny", Message: "missing <owner>", Details: map[string]interface{}{"missing": []string{"owner"}}},
+	}
+	report.AddResult(failed)
+
+	passed := &reporting.Result{}
+	passed.Object = newObject("v1", "Namespace", "", "default")
+	report.AddResult(passed)
+	report.AddError(reporting.Source{File: "broken.yaml"}, errors.New("invalid yaml"))
+
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteHTML(&buf, report))
+
+	out := buf.String()
+	assert.Contains(t, out, `<details class="resource failed" data-constraints="K8sRequiredLabels/owner" data-namespace="default" data-kind="Deployment">`)
+	assert.Contains(t, out, `<details class="resource passed" data-constraints="" data-namespace="" data-kind="Namespace">`)
+	assert.Contains(t, out, `<option value="">(cluster)</option>`)
+	assert.Contains(t, out, `<a href="#template-k8srequiredlabels">k8srequiredlabels</a>`)
+	assert.Contains(t, out, `<dt id="template-k8srequiredlabels">`)
+	assert.Contains(t, out, "Requires &lt;labels&gt;.")
+	assert.Contains(t, out, "missing &lt;owner&gt;")
+	assert.Contains(t, out, "&#34;missing&#34;: [")
+	assert.Contains(t, out, "<code>broken.yaml</code>: invalid yaml")
+	assert.NotContains(t, out, "<link")
+	assert.NotContains(t, out, "src=")
+}
@@ -0,0 +1,132 @@
+package reporting
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONAPIVersion is the version of the JSON report. Fields may be added to
+// a version, but are never removed nor change meaning.
+const JSONAPIVersion = "gatepeeker/v1"
+
+type jsonReport struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Summary    *Summary      `json:"summary"`
+	Results    []*jsonResult `json:"results"`
+	Errors     []*jsonError  `json:"errors"`
+}
+
+type jsonError struct {
+	Source  jsonSource `json:"source"`
+	Message string     `json:"message"`
+}
+
+type jsonResult struct {
+	Resource   jsonResource     `json:"resource"`
+	Source     jsonSource       `json:"source"`
+	Status     string           `json:"status"`
+	Violations []*jsonViolation `json:"violations"`
+}
+
+type jsonResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+type jsonSource struct {
+	File     string `json:"file"`
+	Variant  string `json:"variant,omitempty"`
+	Template string `json:"template,omitempty"`
+	Document int    `json:"document"`
+	Item     int    `json:"item,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+type jsonViolation struct {
+	Constraint *jsonConstraint `json:"constraint,omitempty"`
+	// Check names the check of gatepeeker which found the violation, when it
+	// wasn't found by a constraint nor by the schema.
+	Check             string                 `json:"check,omitempty"`
+	Template          string                 `json:"template,omitempty"`
+	EnforcementAction string                 `json:"enforcementAction"`
+	Message           string                 `json:"message"`
+	Target            string                 `json:"target"`
+	Engine            string                 `json:"engine,omitempty"`
+	Details           map[string]interface{} `json:"details,omitempty"`
+}
+
+type jsonConstraint struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+// WriteJSON writes the report as a JSON document, versioned by
+// JSONAPIVersion.
+func WriteJSON(w io.Writer, r *Report) error {
+	out := &jsonReport{}
+	out.APIVersion = JSONAPIVersion
+	out.Kind = "Report"
+	out.Summary = r.Summary()
+	out.Results = []*jsonResult{}
+	out.Errors = []*jsonError{}
+
+	for _, result := range r.results {
+		res := &jsonResult{}
+		res.Resource.APIVersion = result.Object.GetAPIVersion()
+		res.Resource.Kind = result.Object.GetKind()
+		res.Resource.Namespace = result.Object.GetNamespace()
+		res.Resource.Name = result.Object.GetName()
+		res.Source = newJSONSource(result.Source)
+		res.Status = "passed"
+		if result.FailureCount() > 0 {
+			res.Status = "failed"
+		}
+		res.Violations = []*jsonViolation{}
+
+		for _, v := range result.Violations {
+			violation := &jsonViolation{}
+			if v.Constraint.Kind != "" {
+				violation.Constraint = &jsonConstraint{}
+				violation.Constraint.APIVersion = v.Constraint.GroupVersion().String()
+				violation.Constraint.Kind = v.Constraint.Kind
+				violation.Constraint.Name = v.ConstraintName
+			} else {
+				violation.Check = v.ConstraintName
+			}
+			violation.Template = v.Template
+			violation.EnforcementAction = v.EnforcementAction
+			violation.Message = v.Message
+			violation.Target = v.Target
+			violation.Engine = v.Engine
+			violation.Details = v.Details
+			res.Violations = append(res.Violations, violation)
+		}
+
+		out.Results = append(out.Results, res)
+	}
+
+	for _, e := range r.errors {
+		out.Errors = append(out.Errors, &jsonError{Source: newJSONSource(e.Source), Message: e.Err.Error()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func newJSONSource(source Source) jsonSource {
+	out := jsonSource{}
+	out.File = source.File
+	out.Variant = source.Variant
+	out.Template = source.Template
+	out.Document = source.Document
+	out.Item = source.Item
+	out.Line = source.Line
+	out.Column = source.Column
+	return out
+}
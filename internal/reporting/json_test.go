@@ -0,0 +1,108 @@
+package reporting_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestWriteJSON(t *testing.T) {
+	report := reporting.New()
+
+	failed := &reporting.Result{}
+	failed.Object = newObject("apps/v1", "Deployment", "default", "web")
+	failed.Source = reporting.Source{File: "deploy.yaml", Document: 1, Line: 7, Column: 1}
+	failed.Violations = []*reporting.Violation{
+		{
+			Constraint:        schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"},
+			ConstraintName:    "must-have-owner",
+			Template:          "k8srequiredlabels",
+			EnforcementAction: "deny",
+			Message:           "you must provide labels: {\"owner\"}",
+			Target:            "admission.k8s.gatekeeper.sh",
+			Engine:            reporting.EngineRego,
+			Details:           map[string]interface{}{"missing_labels": []interface{}{"owner"}},
+		},
+		{
+			EnforcementAction: "deny",
+			Message:           "spec.replica: unknown field",
+			Target:            "openapi",
+			Engine:            reporting.EngineOpenAPI,
+		},
+	}
+	report.AddResult(failed)
+
+	other := reporting.New()
+	passed := &reporting.Result{}
+	passed.Object = newObject("v1", "Namespace", "", "default")
+	passed.Source = reporting.Source{File: "chart", Variant: "values-prod.yaml", Template: "chart/templates/ns.yaml"}
+	other.AddResult(passed)
+	other.AddError(reporting.Source{File: "broken.yaml", Document: 2, Line: 9}, errors.New("yaml: line 9: did not find expected key"))
+	report.Merge(other)
+
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteJSON(&buf, report))
+	assert.JSONEq(t, `{
+  "apiVersion": "gatepeeker/v1",
+  "kind": "Report",
+  "summary": {
+    "resources": 2,
+    "passed": 1,
+    "failed": 1,
+    "violations": 2,
+    "errors": 1,
+    "enforcementActions": {"deny": 2}
+  },
+  "results": [
+    {
+      "resource": {"apiVersion": "apps/v1", "kind": "Deployment", "namespace": "default", "name": "web"},
+      "source": {"file": "deploy.yaml", "document": 1, "line": 7, "column": 1},
+      "status": "failed",
+      "violations": [
+        {
+          "constraint": {"apiVersion": "constraints.gatekeeper.sh/v1beta1", "kind": "K8sRequiredLabels", "name": "must-have-owner"},
+          "template": "k8srequiredlabels",
+          "enforcementAction": "deny",
+          "message": "you must provide labels: {\"owner\"}",
+          "target": "admission.k8s.gatekeeper.sh",
+          "engine": "Rego",
+          "details": {"missing_labels": ["owner"]}
+        },
+        {
+          "enforcementAction": "deny",
+          "message": "spec.replica: unknown field",
+          "target": "openapi",
+          "engine": "OpenAPI"
+        }
+      ]
+    },
+    {
+      "resource": {"apiVersion": "v1", "kind": "Namespace", "name": "default"},
+      "source": {"file": "chart", "variant": "values-prod.yaml", "template": "chart/templates/ns.yaml", "document": 0},
+      "status": "passed",
+      "violations": []
+    }
+  ],
+  "errors": [
+    {
+      "source": {"file": "broken.yaml", "document": 2, "line": 9},
+      "message": "yaml: line 9: did not find expected key"
+    }
+  ]
+}`, buf.String())
+}
@@ -0,0 +1,207 @@
+package reporting
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// How test cases are grouped into suites by JUnit.
+const (
+	// JUnitSuitesByFile makes a suite of each input, holding a test case per
+	// resource and matched constraint.
+	JUnitSuitesByFile = "file"
+	// JUnitSuitesByConstraint makes a suite of each constraint, holding a
+	// test case per resource it matched.
+	JUnitSuitesByConstraint = "constraint"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+
+	seconds float64
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit returns a format writing the report as JUnit XML, with suites grouped
+// by JUnitSuitesByFile or JUnitSuitesByConstraint.
+//
+// Denials are failures, other enforcement actions are written to the
+// system-out of their test case, and inputs which couldn't be validated are
+// errors. Constraints which don't match a resource are left out.
+func JUnit(suites string) (Format, error) {
+	switch suites {
+	case JUnitSuitesByFile, JUnitSuitesByConstraint:
+	default:
+		return nil, fmt.Errorf("unknown JUnit suites %q, expected %s or %s", suites, JUnitSuitesByFile, JUnitSuitesByConstraint)
+	}
+	return func(w io.Writer, r *Report) error {
+		return writeJUnit(w, r, suites)
+	}, nil
+}
+
+func writeJUnit(w io.Writer, r *Report, suitesBy string) error {
+	var (
+		suites []*junitTestSuite
+		byName = map[string]*junitTestSuite{}
+	)
+	suite := func(name string) *junitTestSuite {
+		s, ok := byName[name]
+		if !ok {
+			s = &junitTestSuite{Name: name}
+			byName[name] = s
+			suites = append(suites, s)
+		}
+		return s
+	}
+
+	for _, result := range r.results {
+		resource := ResourceName(result.Object)
+		for _, e := range result.Evaluations {
+			if e.Skipped {
+				continue
+			}
+
+			tc := &junitTestCase{}
+			tc.File = result.Source.File
+			tc.Line = result.Source.Line
+			tc.Time = junitSeconds(result.Duration.Seconds())
+
+			var s *junitTestSuite
+			switch suitesBy {
+			case JUnitSuitesByConstraint:
+				s = suite(evaluationName(e))
+				tc.Name = resource
+				tc.ClassName = result.Source.Input()
+			default:
+				s = suite(result.Source.Input())
+				tc.Name = evaluationName(e)
+				tc.ClassName = resource
+			}
+
+			var (
+				denials, others []string
+				message         string
+			)
+			for _, v := range result.violations(e) {
+				if v.EnforcementAction == "deny" {
+					if message == "" {
+						message = v.Message
+					}
+					denials = append(denials, withDetails(v.Message, v))
+					continue
+				}
+				others = append(others, withDetails(fmt.Sprintf("%s: %s", v.EnforcementAction, v.Message), v))
+			}
+			if len(denials) > 0 {
+				tc.Failure = &junitMessage{}
+				tc.Failure.Message = message
+				tc.Failure.Type = "deny"
+				tc.Failure.Text = fmt.Sprintf("%s\n\n%s", strings.Join(denials, "\n"), result.Source.String())
+				s.Failures++
+			}
+			tc.SystemOut = strings.Join(others, "\n")
+
+			s.Tests++
+			s.seconds += result.Duration.Seconds()
+			s.TestCases = append(s.TestCases, tc)
+		}
+	}
+
+	for _, e := range r.errors {
+		s := suite(e.Source.Input())
+		tc := &junitTestCase{}
+		tc.Name = fmt.Sprintf("document %d", e.Source.Document)
+		tc.ClassName = e.Source.Input()
+		tc.File = e.Source.File
+		tc.Line = e.Source.Line
+		tc.Time = junitSeconds(0)
+		tc.Error = &junitMessage{}
+		tc.Error.Message = e.Err.Error()
+		tc.Error.Type = "error"
+		tc.Error.Text = fmt.Sprintf("%s\n\n%s", e.Err, e.Source.String())
+		s.Tests++
+		s.Errors++
+		s.TestCases = append(s.TestCases, tc)
+	}
+
+	out := &junitTestSuites{}
+	out.Name = "gatepeeker"
+	var seconds float64
+	for _, s := range suites {
+		s.Time = junitSeconds(s.seconds)
+		out.Tests += s.Tests
+		out.Failures += s.Failures
+		out.Errors += s.Errors
+		seconds += s.seconds
+	}
+	out.Time = junitSeconds(seconds)
+	out.Suites = suites
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// withDetails appends the details of v to msg, pretty-printed on the following
+// lines.
+func withDetails(msg string, v *Violation) string {
+	if details := formatDetails(v.Details, "  "); details != "" {
+		return msg + "\n" + details
+	}
+	return msg
+}
+
+// evaluationName names the constraint of e, or the check.
+func evaluationName(e *Evaluation) string {
+	if e.Constraint.Kind == "" {
+		if e.ConstraintName != "" {
+			return e.ConstraintName
+		}
+		return "schema"
+	}
+	return e.Constraint.Kind + "/" + e.ConstraintName
+}
+
+func junitSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
@@ -0,0 +1,139 @@
+package reporting_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type junitCase struct {
+	Name      string `xml:"name,attr"`
+	ClassName string `xml:"classname,attr"`
+	Failure   *struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	} `xml:"failure"`
+	Error *struct {
+		Message string `xml:"message,attr"`
+	} `xml:"error"`
+	SystemOut string `xml:"system-out"`
+}
+
+type junitSuites struct {
+	Tests    int `xml:"tests,attr"`
+	Failures int `xml:"failures,attr"`
+	Errors   int `xml:"errors,attr"`
+	Suites   []struct {
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Cases    []junitCase `xml:"testcase"`
+	} `xml:"testsuite"`
+}
+
+func junitReport() *reporting.Report {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+	probes := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredProbes"}
+	evaluations := func() []*reporting.Evaluation {
+		return []*reporting.Evaluation{
+			{Constraint: labels, ConstraintName: "owner"},
+			{Constraint: probes, ConstraintName: "probes"},
+			{Constraint: labels, ConstraintName: "team", Skipped: true},
+		}
+	}
+
+	report := reporting.New()
+	web := &reporting.Result{}
+	web.Object = newObject("apps/v1", "Deployment", "default", "web")
+	web.Source = reporting.Source{File: "web.yaml"}
+	web.Duration = 5 * time.Millisecond
+	web.Evaluations = evaluations()
+	web.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "owner", EnforcementAction: "deny", Message: "missing owner", Details: map[string]interface{}{"missing_labels": []interface{}{"owner"}}},
+		{Constraint: probes, ConstraintName: "probes", EnforcementAction: "warn", Message: "missing probes"},
+	}
+	report.AddResult(web)
+
+	api := &reporting.Result{}
+	api.Object = newObject("apps/v1", "Deployment", "default", "api")
+	api.Source = reporting.Source{File: "api.yaml"}
+	api.Evaluations = evaluations()
+	report.AddResult(api)
+
+	report.AddError(reporting.Source{File: "api.yaml", Document: 1, Line: 12}, errors.New("did not find expected key"))
+	return report
+}
+
+func writeJUnit(t *testing.T, suites string) junitSuites {
+	t.Helper()
+	opts := reporting.DefaultFormatOptions()
+	opts.JUnitSuites = suites
+	format, err := reporting.FormatByName("junit", opts)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, format(&buf, junitReport()))
+
+	var out junitSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &out))
+	return out
+}
+
+func TestWriteJUnitByFile(t *testing.T) {
+	out := writeJUnit(t, reporting.JUnitSuitesByFile)
+
+	assert.Equal(t, 5, out.Tests)
+	assert.Equal(t, 1, out.Failures)
+	assert.Equal(t, 1, out.Errors)
+	require.Len(t, out.Suites, 2)
+
+	web := out.Suites[0]
+	assert.Equal(t, "web.yaml", web.Name)
+	assert.Equal(t, 2, web.Tests)
+	assert.Equal(t, 1, web.Failures)
+	require.Len(t, web.Cases, 2)
+	assert.Equal(t, "K8sRequiredLabels/owner", web.Cases[0].Name)
+	assert.Equal(t, "apps:v1:Deployment:default:web", web.Cases[0].ClassName)
+	require.NotNil(t, web.Cases[0].Failure)
+	assert.Equal(t, "missing owner", web.Cases[0].Failure.Message)
+	assert.Equal(t, "missing owner\n{\n  \"missing_labels\": [\n    \"owner\"\n  ]\n}\n\nweb.yaml", web.Cases[0].Failure.Text)
+	assert.Nil(t, web.Cases[1].Failure)
+	assert.Equal(t, "warn: missing probes", web.Cases[1].SystemOut)
+
+	api := out.Suites[1]
+	assert.Equal(t, "api.yaml", api.Name)
+	require.Len(t, api.Cases, 3)
+	assert.Equal(t, "document 1", api.Cases[2].Name)
+	require.NotNil(t, api.Cases[2].Error)
+	assert.Equal(t, "did not find expected key", api.Cases[2].Error.Message)
+}
+
+func TestWriteJUnitByConstraint(t *testing.T) {
+	out := writeJUnit(t, reporting.JUnitSuitesByConstraint)
+
+	require.Len(t, out.Suites, 3)
+	assert.Equal(t, "K8sRequiredLabels/owner", out.Suites[0].Name)
+	assert.Equal(t, 2, out.Suites[0].Tests)
+	assert.Equal(t, 1, out.Suites[0].Failures)
+	assert.Equal(t, "apps:v1:Deployment:default:web", out.Suites[0].Cases[0].Name)
+	assert.Equal(t, "web.yaml", out.Suites[0].Cases[0].ClassName)
+	assert.Equal(t, "K8sRequiredProbes/probes", out.Suites[1].Name)
+	assert.Equal(t, "api.yaml", out.Suites[2].Name)
+}
+
+func TestJUnitUnknownSuites(t *testing.T) {
+	_, err := reporting.JUnit("namespace")
+	assert.Error(t, err)
+
+	opts := reporting.DefaultFormatOptions()
+	opts.JUnitSuites = "namespace"
+	_, err = reporting.FormatByName("junit", opts)
+	assert.Error(t, err)
+}
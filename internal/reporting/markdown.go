@@ -0,0 +1,182 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MarkdownMaxSize is the default size cap of the Markdown report, below the
+// 65536 characters GitHub accepts in a comment.
+const MarkdownMaxSize = 60000
+
+// Markdown returns a format writing the report as Markdown, for pull request
+// comments: a table summing up the violations per constraint, followed by the
+// violations of each resource in collapsible sections.
+//
+// Sections which would make the report larger than maxSize bytes are left
+// out, and a notice tells how many. The summary is always written, and the
+// inputs which couldn't be validated are kept over the sections of resources
+// when they fit. A maxSize of 0 disables the cap.
+func Markdown(maxSize int) Format {
+	return func(w io.Writer, r *Report) error {
+		return writeMarkdown(w, r, maxSize)
+	}
+}
+
+type markdownRow struct {
+	name      string
+	denials   int
+	warnings  int
+	resources map[string]bool
+}
+
+func writeMarkdown(w io.Writer, r *Report, maxSize int) error {
+	var (
+		b       strings.Builder
+		rows    = map[string]*markdownRow{}
+		failed  int
+		failing []*Result
+	)
+	for _, result := range r.results {
+		if result.FailureCount() > 0 {
+			failed++
+		}
+		if len(result.Violations) > 0 {
+			failing = append(failing, result)
+		}
+		for _, v := range result.Violations {
+			name := violationName(v)
+			row, ok := rows[name]
+			if !ok {
+				row = &markdownRow{name: name, resources: map[string]bool{}}
+				rows[name] = row
+			}
+			if v.EnforcementAction == "deny" {
+				row.denials++
+			} else {
+				row.warnings++
+			}
+			row.resources[ResourceName(result.Object)] = true
+		}
+	}
+
+	status := ":white_check_mark:"
+	if failed > 0 || len(r.errors) > 0 {
+		status = ":x:"
+	}
+	fmt.Fprintf(&b, "### %s gatepeeker\n\n", status)
+	fmt.Fprintf(&b, "%d resources validated, %d failed", len(r.results), failed)
+	if len(r.errors) > 0 {
+		fmt.Fprintf(&b, ", %d errors", len(r.errors))
+	}
+	b.WriteString(".\n\n")
+
+	if len(rows) > 0 {
+		sorted := make([]*markdownRow, 0, len(rows))
+		for _, row := range rows {
+			sorted = append(sorted, row)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].denials != sorted[j].denials {
+				return sorted[i].denials > sorted[j].denials
+			}
+			return sorted[i].name < sorted[j].name
+		})
+
+		b.WriteString("| Constraint | Denials | Warnings | Resources |\n")
+		b.WriteString("| --- | ---: | ---: | ---: |\n")
+		for _, row := range sorted {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", escapeMarkdown(row.name), row.denials, row.warnings, len(row.resources))
+		}
+		b.WriteString("\n")
+	}
+
+	var (
+		sections []string
+		errs     string
+		size     = b.Len()
+		left     int
+	)
+	for _, result := range failing {
+		sections = append(sections, markdownResult(result))
+		size += len(sections[len(sections)-1])
+	}
+	if len(r.errors) > 0 {
+		errs = markdownErrors(r.errors)
+		size += len(errs)
+	}
+
+	if maxSize > 0 && size > maxSize {
+		// The notice is sized for the most sections it may count, and the
+		// errors are kept over the sections of resources when they fit.
+		budget := maxSize - b.Len() - len(markdownNotice(len(sections)+1))
+		if len(errs) > budget {
+			errs = ""
+			left++
+		} else {
+			budget -= len(errs)
+		}
+		for i, section := range sections {
+			if len(section) > budget {
+				left += len(sections) - i
+				sections = sections[:i]
+				break
+			}
+			budget -= len(section)
+		}
+	}
+
+	for _, section := range sections {
+		b.WriteString(section)
+	}
+	if left > 0 {
+		b.WriteString(markdownNotice(left))
+	}
+	b.WriteString(errs)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownNotice(left int) string {
+	return fmt.Sprintf("> [!NOTE]\n> %d more sections were left out to fit the size limit, see the full report for details.\n\n", left)
+}
+
+func markdownResult(result *Result) string {
+	var b strings.Builder
+	icon := ":warning:"
+	if result.FailureCount() > 0 {
+		icon = ":x:"
+	}
+	fmt.Fprintf(&b, "<details>\n<summary>%s <code>%s</code> (%s)</summary>\n\n", icon, escapeHTML(ResourceName(result.Object)), escapeHTML(result.Source.String()))
+	for _, v := range result.Violations {
+		name := violationName(v)
+		fmt.Fprintf(&b, "- **%s** %s: %s\n", v.EnforcementAction, escapeMarkdown(name), escapeMarkdown(v.Message))
+		if details := formatDetails(v.Details, "  "); details != "" {
+			fmt.Fprintf(&b, "\n  ```json\n  %s\n  ```\n\n", strings.ReplaceAll(details, "\n", "\n  "))
+		}
+	}
+	b.WriteString("\n</details>\n\n")
+	return b.String()
+}
+
+func markdownErrors(errs []*InputError) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<details>\n<summary>:boom: %d inputs could not be validated</summary>\n\n", len(errs))
+	for _, e := range errs {
+		fmt.Fprintf(&b, "- `%s`: %s\n", e.Source.String(), escapeMarkdown(e.Err.Error()))
+	}
+	b.WriteString("\n</details>\n\n")
+	return b.String()
+}
+
+// escapeMarkdown keeps s on a single line, and from breaking tables.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("\n", " ", "|", "\\|", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func escapeHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
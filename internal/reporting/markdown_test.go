@@ -0,0 +1,67 @@
+package reporting_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func markdownReport(resources int) *reporting.Report {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+	probes := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredProbes"}
+
+	report := reporting.New()
+	for i := 0; i < resources; i++ {
+		result := &reporting.Result{}
+		result.Object = newObject("apps/v1", "Deployment", "default", fmt.Sprintf("web-%d", i))
+		result.Source = reporting.Source{File: "web.yaml", Document: i}
+		result.Violations = []*reporting.Violation{
+			{Constraint: labels, ConstraintName: "owner", EnforcementAction: "deny", Message: "missing | owner", Details: map[string]interface{}{"missing_labels": []interface{}{"owner"}}},
+			{Constraint: probes, ConstraintName: "probes", EnforcementAction: "warn", Message: "missing probes"},
+		}
+		report.AddResult(result)
+	}
+
+	passed := &reporting.Result{}
+	passed.Object = newObject("v1", "Namespace", "", "default")
+	report.AddResult(passed)
+	return report
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, reporting.Markdown(0)(&buf, markdownReport(2)))
+
+	out := buf.String()
+	assert.Contains(t, out, "### :x: gatepeeker\n\n3 resources validated, 2 failed.\n")
+	assert.Contains(t, out, ""+
+		"| Constraint | Denials | Warnings | Resources |\n"+
+		"| --- | ---: | ---: | ---: |\n"+
+		"| K8sRequiredLabels/owner | 2 | 0 | 2 |\n"+
+		"| K8sRequiredProbes/probes | 0 | 2 | 2 |\n")
+	assert.Contains(t, out, "<summary>:x: <code>apps:v1:Deployment:default:web-1</code> (web.yaml)</summary>")
+	assert.Contains(t, out, "- **deny** K8sRequiredLabels/owner: missing \\| owner\n\n  ```json\n  {\n    \"missing_labels\": [\n      \"owner\"\n    ]\n  }\n  ```\n\n")
+	assert.NotContains(t, out, "[!NOTE]")
+}
+
+func TestWriteMarkdownTruncated(t *testing.T) {
+	report := markdownReport(50)
+	report.AddError(reporting.Source{File: "broken.yaml", Line: 3}, errors.New("did not find expected key"))
+
+	for _, maxSize := range []int{1000, 2000, 5000} {
+		var buf bytes.Buffer
+		require.NoError(t, reporting.Markdown(maxSize)(&buf, report))
+
+		out := buf.String()
+		assert.LessOrEqual(t, len(out), maxSize)
+		assert.Contains(t, out, "| K8sRequiredLabels/owner | 50 | 0 | 50 |")
+		assert.Contains(t, out, "- `broken.yaml:3:0`: did not find expected key")
+		assert.Regexp(t, `> \d+ more sections were left out to fit the size limit`, out)
+	}
+}
@@ -0,0 +1,281 @@
+package reporting
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// RedactedValue replaces the redacted values.
+const RedactedValue = "[REDACTED]"
+
+// minRedactedLength is the length below which masked values aren't replaced
+// in messages, where they would more likely match unrelated text than leak.
+const minRedactedLength = 4
+
+// Redactor masks sensitive values in a report: the data and stringData of
+// Secrets, the values found at the paths it was given, and the matches of its
+// patterns. Values are masked in the reported objects, and wherever they are
+// echoed by messages, details and errors.
+type Redactor struct {
+	rules    []*redactRule
+	patterns []*regexp.Regexp
+}
+
+type redactRule struct {
+	// kind restricts the rule to the resources of a kind, when not empty.
+	kind  string
+	steps []pathStep
+	// base64 marks the values as base64 encoded, so that their decoded value
+	// is redacted as well.
+	base64 bool
+}
+
+// pathStep is a step of a path: a key, an index, or any key or index when
+// wildcard is true.
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// NewRedactor returns a Redactor masking the data of Secrets, along with the
+// values at paths and the matches of patterns.
+//
+// Paths are JSON paths like .spec.containers[*].env[*].value, with keys
+// separated by dots, indexes or * in brackets, and * matching any key. A path
+// applies to every resource, unless prefixed by a kind, like
+// ConfigMap:.data.token. Patterns are regular expressions.
+func NewRedactor(paths, patterns []string) (*Redactor, error) {
+	r := &Redactor{}
+	r.rules = []*redactRule{
+		{kind: "Secret", steps: []pathStep{{key: "data"}, {wildcard: true}}, base64: true},
+		{kind: "Secret", steps: []pathStep{{key: "stringData"}, {wildcard: true}}},
+	}
+	for _, p := range paths {
+		rule, err := parseRedactRule(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redacted path %q: %w", p, err)
+		}
+		r.rules = append(r.rules, rule)
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redacted pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func parseRedactRule(p string) (*redactRule, error) {
+	rule := &redactRule{}
+	if i := strings.Index(p, ":"); i > 0 && !strings.ContainsAny(p[:i], "$.[") {
+		rule.kind, p = p[:i], p[i+1:]
+	}
+	p = strings.TrimPrefix(p, "$")
+	if p == "" {
+		return nil, errors.New("empty path")
+	}
+
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end < 0 {
+				end = len(p)
+			}
+			key := p[:end]
+			if key == "" {
+				return nil, errors.New("empty key")
+			}
+			rule.steps = append(rule.steps, pathStep{key: key, wildcard: key == "*"})
+			p = p[end:]
+		case '[':
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, errors.New("unterminated bracket")
+			}
+			value := p[1:end]
+			p = p[end+1:]
+			if value == "*" {
+				rule.steps = append(rule.steps, pathStep{wildcard: true})
+				continue
+			}
+			index, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", value)
+			}
+			rule.steps = append(rule.steps, pathStep{index: index, isIndex: true})
+		default:
+			return nil, fmt.Errorf("expected . or [ at %q", p)
+		}
+	}
+	return rule, nil
+}
+
+// Redact masks the sensitive values of every result and error of report.
+func (r *Redactor) Redact(report *Report) {
+	for _, result := range report.results {
+		r.redactResult(result)
+	}
+	for _, e := range report.errors {
+		if msg := r.redactString(e.Err.Error(), nil); msg != e.Err.Error() {
+			e.Err = errors.New(msg)
+		}
+	}
+}
+
+func (r *Redactor) redactResult(result *Result) {
+	var values []string
+	if result.Object != nil {
+		result.Object, values = r.mask(result.Object)
+	}
+
+	for _, v := range result.Violations {
+		v.Message = r.redactString(v.Message, values)
+		if v.Details != nil {
+			v.Details = r.redactValue(v.Details, values).(map[string]interface{})
+		}
+	}
+}
+
+// RedactMessage masks the sensitive values of obj, and the matches of the
+// patterns, in msg, for the messages logged or returned before the report is
+// written. obj may be nil, and a nil Redactor returns msg as is.
+func (r *Redactor) RedactMessage(msg string, obj *unstructured.Unstructured) string {
+	if r == nil {
+		return msg
+	}
+	var values []string
+	if obj != nil {
+		_, values = r.mask(obj)
+	}
+	return r.redactString(msg, values)
+}
+
+// mask returns a copy of obj with its sensitive values masked, along with the
+// values it masked, longer values first, so that a value containing another is
+// masked as a whole.
+func (r *Redactor) mask(obj *unstructured.Unstructured) (*unstructured.Unstructured, []string) {
+	var values []string
+	obj = obj.DeepCopy()
+	for _, rule := range r.rules {
+		if rule.kind != "" && rule.kind != obj.GetKind() {
+			continue
+		}
+		var found []string
+		obj.Object = maskPath(obj.Object, rule.steps, &found).(map[string]interface{})
+		for _, value := range found {
+			if len(value) < minRedactedLength {
+				continue
+			}
+			values = append(values, value)
+			if !rule.base64 {
+				continue
+			}
+			if decoded, err := base64.StdEncoding.DecodeString(value); err == nil && len(decoded) >= minRedactedLength {
+				values = append(values, string(decoded))
+			}
+		}
+	}
+	if len(r.patterns) > 0 {
+		obj.Object = r.redactValue(obj.Object, nil).(map[string]interface{})
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return obj, values
+}
+
+func (r *Redactor) redactString(s string, values []string) string {
+	for _, value := range values {
+		s = strings.ReplaceAll(s, value, RedactedValue)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, RedactedValue)
+	}
+	return s
+}
+
+// redactValue returns a copy of node, with its strings redacted.
+func (r *Redactor) redactValue(node interface{}, values []string) interface{} {
+	switch n := node.(type) {
+	case string:
+		return r.redactString(n, values)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			out[k] = r.redactValue(v, values)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, v := range n {
+			out[i] = r.redactValue(v, values)
+		}
+		return out
+	}
+	return node
+}
+
+// maskPath replaces the values of node at steps with RedactedValue, appending
+// the strings it replaced to found. node is modified in place.
+func maskPath(node interface{}, steps []pathStep, found *[]string) interface{} {
+	if len(steps) == 0 {
+		return maskAll(node, found)
+	}
+	step, rest := steps[0], steps[1:]
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if step.isIndex {
+			return node
+		}
+		for k, v := range n {
+			if step.wildcard || k == step.key {
+				n[k] = maskPath(v, rest, found)
+			}
+		}
+	case []interface{}:
+		if !step.isIndex && !step.wildcard {
+			return node
+		}
+		for i, v := range n {
+			if step.wildcard || i == step.index {
+				n[i] = maskPath(v, rest, found)
+			}
+		}
+	}
+	return node
+}
+
+// maskAll replaces every value held by node with RedactedValue, keeping the
+// keys of objects and the length of arrays.
+func maskAll(node interface{}, found *[]string) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			n[k] = maskAll(v, found)
+		}
+		return n
+	case []interface{}:
+		for i, v := range n {
+			n[i] = maskAll(v, found)
+		}
+		return n
+	case nil:
+		return nil
+	case string:
+		*found = append(*found, n)
+	default:
+		*found = append(*found, fmt.Sprint(n))
+	}
+	return RedactedValue
+}
@@ -1,21 +1,45 @@
 package reporting
 
 import (
+	"encoding/json"
 	"fmt"
-	"io"
+	"path"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type Report struct {
-	results      map[string]*Result
+	results   []*Result
+	errors    []*InputError
+	templates []*Template
+	// keys maps the keys of the results to their index in results.
+	keys         map[string]int
 	failureCount int
 }
 
+// Template describes a ConstraintTemplate, for the formats listing the
+// policies along with their results.
+type Template struct {
+	Name string
+	// Kind is the kind of the constraints of the template.
+	Kind        string
+	Title       string
+	Description string
+}
+
+// InputError is an input, or a document of an input, which couldn't be
+// validated.
+type InputError struct {
+	Source Source
+	Err    error
+}
+
 func New() *Report {
 	r := &Report{}
-	r.results = make(map[string]*Result)
+	r.keys = make(map[string]int)
 	return r
 }
 
@@ -23,26 +47,109 @@ func (r *Report) FailureCount() int {
 	return r.failureCount
 }
 
+// Results returns the results in the order they were added.
+func (r *Report) Results() []*Result {
+	return r.results
+}
+
+// AddResult records the result of a resource. Resources are identified by
+// their identity and their position in the input, the document and the item
+// of a List, so the same resource may be found in several inputs, or several
+// times in a List. A result of a resource already recorded at the same
+// position replaces it.
 func (r *Report) AddResult(result *Result) {
-	key := r.buildKey(result.Object)
-	_, exists := r.results[key]
-	if exists {
-		panic("already exists")
+	key := r.buildKey(result)
+	if i, ok := r.keys[key]; ok {
+		r.failureCount -= r.results[i].FailureCount()
+		r.results[i] = result
+		r.failureCount += result.FailureCount()
+		return
 	}
-	r.results[key] = result
+	r.keys[key] = len(r.results)
+	r.results = append(r.results, result)
 	r.failureCount += result.FailureCount()
 }
 
-func (r *Report) WriteTo(w io.Writer) {
-	for key, value := range r.results {
-		fmt.Fprintf(w, "%s %s\n", value.isValid(), key)
-		for _, warning := range value.Warnings {
-			fmt.Fprintf(w, "  WARNING %s\n", warning)
+// AddError records an input, or a document of an input, which couldn't be
+// validated.
+func (r *Report) AddError(source Source, err error) {
+	r.errors = append(r.errors, &InputError{Source: source, Err: err})
+}
+
+// AddTemplate records a template the resources were validated against.
+func (r *Report) AddTemplate(t *Template) {
+	r.templates = append(r.templates, t)
+}
+
+// Templates returns the templates in the order they were added.
+func (r *Report) Templates() []*Template {
+	return r.templates
+}
+
+// Errors returns the errors in the order they were added.
+func (r *Report) Errors() []*InputError {
+	return r.errors
+}
+
+// Summary counts the results, violations and errors of a report.
+type Summary struct {
+	Resources  int `json:"resources"`
+	Passed     int `json:"passed"`
+	Failed     int `json:"failed"`
+	Violations int `json:"violations"`
+	Errors     int `json:"errors"`
+	// EnforcementActions counts the violations per enforcement action.
+	EnforcementActions map[string]int `json:"enforcementActions"`
+}
+
+// Summary returns the counts of the report.
+func (r *Report) Summary() *Summary {
+	s := &Summary{}
+	s.EnforcementActions = map[string]int{}
+	for _, result := range r.results {
+		s.Resources++
+		if result.FailureCount() > 0 {
+			s.Failed++
+		} else {
+			s.Passed++
 		}
-		for _, deny := range value.Denials {
-			fmt.Fprintf(w, "  FAILED %s\n", deny)
+		s.Violations += len(result.Violations)
+		for _, v := range result.Violations {
+			s.EnforcementActions[v.EnforcementAction]++
 		}
 	}
+	s.Errors = len(r.errors)
+	return s
+}
+
+// Merge adds the results and errors of other, which were read from other
+// sources.
+func (r *Report) Merge(other *Report) {
+	for _, result := range other.results {
+		r.AddResult(result)
+	}
+	r.errors = append(r.errors, other.errors...)
+}
+
+// formatDetails returns the details of a violation as JSON, indented with
+// indent when it isn't empty, or an empty string when there are none.
+func formatDetails(details map[string]interface{}, indent string) string {
+	if len(details) == 0 {
+		return ""
+	}
+	var (
+		out []byte
+		err error
+	)
+	if indent != "" {
+		out, err = json.MarshalIndent(details, "", indent)
+	} else {
+		out, err = json.Marshal(details)
+	}
+	if err != nil {
+		return fmt.Sprint(details)
+	}
+	return string(out)
 }
 
 func ResourceName(obj *unstructured.Unstructured) string {
@@ -53,23 +160,156 @@ func ResourceName(obj *unstructured.Unstructured) string {
 	return strings.ReplaceAll(fmt.Sprintf("%s:%s:%s:%s", apiVersion, kind, namespace, name), "/", ":")
 }
 
-func (r *Report) buildKey(obj *unstructured.Unstructured) string {
-	return ResourceName(obj)
+func (r *Report) buildKey(result *Result) string {
+	return fmt.Sprintf("%s#%d.%d %s", result.Source.Input(), result.Source.Document, result.Source.Item, ResourceName(result.Object))
+}
+
+// Source describes where a resource was read from.
+type Source struct {
+	// File is the file, directory or chart the resource was read from.
+	File string
+	// Variant distinguishes the renders of the same File, like the values
+	// files a chart was rendered with.
+	Variant string
+	// Template is the chart template the resource was rendered from, as
+	// found in the "# Source:" comment written by helm.
+	Template string
+	// Document is the index of the document holding the resource in File,
+	// starting at 0.
+	Document int
+	// Item is the position of the resource in the items of the List of
+	// Document, starting at 1. It is 0 when Document isn't a List.
+	Item int
+	// Line and Column locate the start of the resource in File, starting
+	// at 1. They are 0 when unknown.
+	Line   int
+	Column int
+	// Rendered is set when the resource was rendered by kustomize or helm.
+	// File is then the directory of the kustomization or the root of the
+	// chart, and Line and Column locate the resource in the rendered output.
+	Rendered bool
+}
+
+// Input returns the file and variant of s, which identify the input the
+// resource was read from.
+func (s Source) Input() string {
+	if s.Variant != "" {
+		return fmt.Sprintf("%s [%s]", s.File, s.Variant)
+	}
+	return s.File
+}
+
+// Location returns the file and line to annotate for the resource, for the
+// formats shown alongside code. Rendered resources are located without a line,
+// since their line is the one of the rendered output: at their template within
+// the chart root, or at the directory of their kustomization.
+func (s Source) Location() (file string, line, column int) {
+	if !s.Rendered {
+		return s.File, s.Line, s.Column
+	}
+	if s.Template != "" {
+		// Templates are named after the chart, not after its directory.
+		_, template, _ := strings.Cut(s.Template, "/")
+		return path.Join(s.File, template), 0, 0
+	}
+	return s.File, 0, 0
+}
+
+func (s Source) String() string {
+	out := s.File
+	if s.Line > 0 && !s.Rendered {
+		out = fmt.Sprintf("%s:%d:%d", out, s.Line, s.Column)
+	}
+	if s.Variant != "" {
+		out = fmt.Sprintf("%s [%s]", out, s.Variant)
+	}
+	if s.Template != "" {
+		out = fmt.Sprintf("%s: %s", out, s.Template)
+	}
+	return out
+}
+
+// CheckDuplicates names the violations, and evaluations, of the check of
+// resources defined more than once in an input.
+const CheckDuplicates = "duplicates"
+
+// The engines a violation may be found by.
+const (
+	EngineRego = "Rego"
+	EngineCEL  = "CEL"
+	// EngineOpenAPI finds the violations of the schema of resources.
+	EngineOpenAPI = "OpenAPI"
+)
+
+// Violation is a constraint, or a schema, a resource doesn't comply with.
+type Violation struct {
+	// Constraint and ConstraintName identify the violated constraint.
+	// Constraint is empty for the checks of gatepeeker, which are named by
+	// ConstraintName, like CheckDuplicates, and both are empty for schema
+	// errors.
+	Constraint     schema.GroupVersionKind
+	ConstraintName string
+	// Template is the name of the ConstraintTemplate defining the
+	// constraint's kind.
+	Template          string
+	EnforcementAction string
+	Message           string
+	// Target is the target of the template which produced the violation,
+	// like admission.k8s.gatekeeper.sh.
+	Target string
+	// Engine is the engine which evaluated the constraint, EngineRego or
+	// EngineCEL, or EngineOpenAPI for schema errors. It is empty for the other
+	// checks of gatepeeker.
+	Engine string
+	// Details holds the details returned along with the message, if any.
+	Details map[string]interface{}
+}
+
+// Evaluation is a constraint, or the schema, a resource was checked against.
+type Evaluation struct {
+	// Constraint and ConstraintName identify the constraint, or the check,
+	// like Violation. They are empty for the schema.
+	Constraint     schema.GroupVersionKind
+	ConstraintName string
+	Template       string
+	// Skipped is true when the constraint doesn't match the resource.
+	Skipped bool
 }
 
 type Result struct {
-	Object   *unstructured.Unstructured
-	Warnings []string
-	Denials  []string
+	Object      *unstructured.Unstructured
+	Source      Source
+	Violations  []*Violation
+	Evaluations []*Evaluation
+	// Start and Duration time the review of the resource.
+	Start    time.Time
+	Duration time.Duration
 }
 
 func (r *Result) isValid() string {
-	if len(r.Denials) > 0 {
+	if r.FailureCount() > 0 {
 		return "FAILED"
 	}
 	return "PASS"
 }
 
+// FailureCount returns the number of violations denying the resource.
 func (r *Result) FailureCount() int {
-	return len(r.Denials)
+	var count int
+	for _, v := range r.Violations {
+		if v.EnforcementAction == "deny" {
+			count++
+		}
+	}
+	return count
+}
+
+// violations returns the violations of r found by e.
+func (r *Result) violations(e *Evaluation) (out []*Violation) {
+	for _, v := range r.Violations {
+		if v.Constraint == e.Constraint && v.ConstraintName == e.ConstraintName {
+			out = append(out, v)
+		}
+	}
+	return out
 }
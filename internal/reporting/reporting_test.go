@@ -0,0 +1,88 @@
+package reporting_test
+
+import (
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportSameIdentity(t *testing.T) {
+	denied := func(file string, document int) *reporting.Result {
+		result := &reporting.Result{}
+		result.Object = newObject("apps/v1", "Deployment", "default", "web")
+		result.Source = reporting.Source{File: file, Document: document}
+		result.Violations = []*reporting.Violation{{EnforcementAction: "deny", Message: "invalid"}}
+		return result
+	}
+
+	report := reporting.New()
+	report.AddResult(denied("overlays/prod", 0))
+	report.AddResult(denied("overlays/prod", 1))
+
+	other := reporting.New()
+	other.AddResult(denied("overlays/staging", 0))
+	report.Merge(other)
+	require.Len(t, report.Results(), 3)
+	assert.Equal(t, 3, report.FailureCount())
+
+	// The same document validated again replaces its result.
+	passed := denied("overlays/prod", 1)
+	passed.Violations = nil
+	report.AddResult(passed)
+	require.Len(t, report.Results(), 3)
+	assert.Same(t, passed, report.Results()[1])
+	assert.Equal(t, 2, report.FailureCount())
+}
+
+func TestReportListItems(t *testing.T) {
+	docs, err := decoding.Decode([]byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: default
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: web
+    namespace: default
+`))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+
+	report := reporting.New()
+	for _, doc := range docs {
+		result := &reporting.Result{}
+		result.Object = doc.Object
+		result.Source = reporting.Source{File: "list.yaml", Document: doc.Index, Item: doc.Item}
+		result.Violations = []*reporting.Violation{{EnforcementAction: "deny", Message: "invalid"}}
+		report.AddResult(result)
+	}
+	require.Len(t, report.Results(), 2)
+	assert.Equal(t, 2, report.FailureCount())
+	assert.Equal(t, 1, report.Results()[0].Source.Item)
+	assert.Equal(t, 2, report.Results()[1].Source.Item)
+}
+
+func TestSourceLocation(t *testing.T) {
+	for _, tt := range []struct {
+		source reporting.Source
+		file   string
+		line   int
+	}{
+		{reporting.Source{File: "web.yaml", Line: 12, Column: 1}, "web.yaml", 12},
+		{reporting.Source{File: "rendered.yaml", Template: "web/templates/service.yaml", Line: 40, Column: 1}, "rendered.yaml", 40},
+		{reporting.Source{File: "charts/frontend", Template: "web/templates/service.yaml", Line: 40, Column: 1, Rendered: true}, "charts/frontend/templates/service.yaml", 0},
+		{reporting.Source{File: "charts/frontend", Template: "web/charts/db/templates/db.yaml", Line: 80, Column: 1, Rendered: true}, "charts/frontend/charts/db/templates/db.yaml", 0},
+		{reporting.Source{File: "overlays/prod", Line: 25, Column: 1, Rendered: true}, "overlays/prod", 0},
+	} {
+		file, line, _ := tt.source.Location()
+		assert.Equal(t, tt.file, file, tt.source.String())
+		assert.Equal(t, tt.line, line, tt.source.String())
+	}
+}
@@ -0,0 +1,186 @@
+package reporting
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifSchemaRule is the id of the rule of schema errors.
+	sarifSchemaRule = "openapi-schema"
+	// sarifDuplicatesRule is the id of the rule of CheckDuplicates.
+	sarifDuplicatesRule = "duplicate-resource"
+)
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name,omitempty"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+	FullDescription  *sarifMessage `json:"fullDescription,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []*sarifLocation       `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log. Each template is a rule,
+// described by its title and description annotations, and each violation is
+// a result located at the resource it was found in. Denials are errors,
+// warnings are warnings, and other enforcement actions are notes.
+func WriteSARIF(w io.Writer, r *Report) error {
+	var (
+		rules   []*sarifRule
+		indexes = map[string]int{}
+	)
+	addRule := func(rule *sarifRule) {
+		if _, ok := indexes[rule.ID]; ok {
+			return
+		}
+		indexes[rule.ID] = len(rules)
+		rules = append(rules, rule)
+	}
+
+	templates := append([]*Template{}, r.templates...)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	byKind := map[string]string{}
+	for _, t := range templates {
+		rule := &sarifRule{}
+		rule.ID = t.Name
+		rule.Name = t.Kind
+		if t.Title != "" {
+			rule.ShortDescription = &sarifMessage{Text: t.Title}
+		}
+		if t.Description != "" {
+			rule.FullDescription = &sarifMessage{Text: t.Description}
+		}
+		addRule(rule)
+		byKind[t.Kind] = t.Name
+	}
+
+	run := &sarifRun{}
+	run.Results = []*sarifResult{}
+	for _, result := range r.results {
+		for _, v := range result.Violations {
+			id := v.Template
+			if id == "" {
+				id = byKind[v.Constraint.Kind]
+			}
+			switch {
+			case v.Constraint.Kind == "" && v.ConstraintName == CheckDuplicates:
+				id = sarifDuplicatesRule
+				addRule(&sarifRule{ID: id, ShortDescription: &sarifMessage{Text: "Resources must be defined once per input"}})
+			case v.Constraint.Kind == "":
+				id = sarifSchemaRule
+				addRule(&sarifRule{ID: id, ShortDescription: &sarifMessage{Text: "Resources must match their OpenAPI schema"}})
+			case id == "":
+				id = v.Constraint.Kind
+				addRule(&sarifRule{ID: id, Name: v.Constraint.Kind})
+			}
+
+			res := &sarifResult{}
+			res.RuleID = id
+			res.RuleIndex = indexes[id]
+			res.Level = sarifLevel(v.EnforcementAction)
+			res.Message.Text = v.Message
+			res.Locations = []*sarifLocation{sarifLocationOf(result.Source)}
+			res.Properties = map[string]interface{}{
+				"resource":          ResourceName(result.Object),
+				"enforcementAction": v.EnforcementAction,
+			}
+			if v.Details != nil {
+				res.Properties["details"] = v.Details
+			}
+			if v.Constraint.Kind != "" {
+				res.Properties["constraint"] = v.Constraint.Kind + "/" + v.ConstraintName
+			}
+			run.Results = append(run.Results, res)
+		}
+	}
+
+	run.Tool.Driver.Name = "gatepeeker"
+	run.Tool.Driver.InformationURI = "https://github.com/limoges/gatepeeker"
+	run.Tool.Driver.Rules = rules
+	if run.Tool.Driver.Rules == nil {
+		run.Tool.Driver.Rules = []*sarifRule{}
+	}
+
+	out := &sarifLog{}
+	out.Schema = sarifSchema
+	out.Version = sarifVersion
+	out.Runs = []*sarifRun{run}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func sarifLevel(action string) string {
+	switch action {
+	case "deny":
+		return "error"
+	case "warn":
+		return "warning"
+	}
+	return "note"
+}
+
+func sarifLocationOf(source Source) *sarifLocation {
+	file, line, column := source.Location()
+	loc := &sarifLocation{}
+	loc.PhysicalLocation.ArtifactLocation.URI = file
+	if line > 0 {
+		loc.PhysicalLocation.Region = &sarifRegion{StartLine: line, StartColumn: column}
+	}
+	return loc
+}
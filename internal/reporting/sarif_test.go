@@ -0,0 +1,97 @@
+package reporting_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+
+	report := reporting.New()
+	report.AddTemplate(&reporting.Template{
+		Name:        "k8srequiredlabels",
+		Kind:        "K8sRequiredLabels",
+		Title:       "Required Labels",
+		Description: "Requires resources to contain specified labels.",
+	})
+
+	deployment := &reporting.Result{}
+	deployment.Object = newObject("apps/v1", "Deployment", "default", "web")
+	deployment.Source = reporting.Source{File: "manifests/web.yaml", Line: 12, Column: 1}
+	deployment.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "owner", Template: "k8srequiredlabels", EnforcementAction: "deny", Message: "missing owner", Details: map[string]interface{}{"missing_labels": []interface{}{"owner"}}},
+		{EnforcementAction: "deny", Message: "spec.replica: unknown field", Target: "openapi"},
+	}
+	report.AddResult(deployment)
+
+	rendered := &reporting.Result{}
+	rendered.Object = newObject("v1", "Service", "default", "web")
+	rendered.Source = reporting.Source{File: "charts/frontend", Template: "web/templates/service.yaml", Line: 40, Rendered: true}
+	rendered.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "team", Template: "k8srequiredlabels", EnforcementAction: "warn", Message: "missing team"},
+	}
+	report.AddResult(rendered)
+
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteSARIF(&buf, report))
+
+	var out map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, "2.1.0", out["version"])
+
+	run := out["runs"].([]interface{})[0].(map[string]interface{})
+	rules := run["tool"].(map[string]interface{})["driver"].(map[string]interface{})["rules"]
+	assert.JSONEq(t, `[
+		{
+			"id": "k8srequiredlabels",
+			"name": "K8sRequiredLabels",
+			"shortDescription": {"text": "Required Labels"},
+			"fullDescription": {"text": "Requires resources to contain specified labels."}
+		},
+		{
+			"id": "openapi-schema",
+			"shortDescription": {"text": "Resources must match their OpenAPI schema"}
+		}
+	]`, toJSON(t, rules))
+
+	assert.JSONEq(t, `[
+		{
+			"ruleId": "k8srequiredlabels",
+			"ruleIndex": 0,
+			"level": "error",
+			"message": {"text": "missing owner"},
+			"locations": [{"physicalLocation": {"artifactLocation": {"uri": "manifests/web.yaml"}, "region": {"startLine": 12, "startColumn": 1}}}],
+			"properties": {"resource": "apps:v1:Deployment:default:web", "enforcementAction": "deny", "constraint": "K8sRequiredLabels/owner", "details": {"missing_labels": ["owner"]}}
+		},
+		{
+			"ruleId": "openapi-schema",
+			"ruleIndex": 1,
+			"level": "error",
+			"message": {"text": "spec.replica: unknown field"},
+			"locations": [{"physicalLocation": {"artifactLocation": {"uri": "manifests/web.yaml"}, "region": {"startLine": 12, "startColumn": 1}}}],
+			"properties": {"resource": "apps:v1:Deployment:default:web", "enforcementAction": "deny"}
+		},
+		{
+			"ruleId": "k8srequiredlabels",
+			"ruleIndex": 0,
+			"level": "warning",
+			"message": {"text": "missing team"},
+			"locations": [{"physicalLocation": {"artifactLocation": {"uri": "charts/frontend/templates/service.yaml"}}}],
+			"properties": {"resource": "v1:Service:default:web", "enforcementAction": "warn", "constraint": "K8sRequiredLabels/team"}
+		}
+	]`, toJSON(t, run["results"]))
+}
+
+func toJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	buf, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(buf)
+}
@@ -0,0 +1,137 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// TemplateViolation is a violation along with the result it was found in, as
+// listed by the violations function of report templates.
+type TemplateViolation struct {
+	*Violation
+	Result *Result
+}
+
+// TemplateGroup is a group of violations sharing a key, as returned by the
+// groupBy function of report templates.
+type TemplateGroup struct {
+	Key        string
+	Violations []*TemplateViolation
+}
+
+// The keys violations can be grouped and counted by in report templates.
+var templateKeys = map[string]func(v *TemplateViolation) string{
+	"constraint": func(v *TemplateViolation) string { return violationName(v.Violation) },
+	"template":   func(v *TemplateViolation) string { return v.Template },
+	"action":     func(v *TemplateViolation) string { return v.EnforcementAction },
+	"engine":     func(v *TemplateViolation) string { return v.Engine },
+	"resource":   func(v *TemplateViolation) string { return ResourceName(v.Result.Object) },
+	"kind":       func(v *TemplateViolation) string { return v.Result.Object.GetKind() },
+	"namespace":  func(v *TemplateViolation) string { return v.Result.Object.GetNamespace() },
+	"file":       func(v *TemplateViolation) string { return v.Result.Source.Input() },
+}
+
+var templateFuncs = template.FuncMap{
+	"resourceName": ResourceName,
+	"constraint":   violationName,
+	"results":      func(r *Report) []*Result { return sortedResults(r.results) },
+	"violations":   templateViolations,
+	"groupBy":      groupBy,
+	"countBy":      countBy,
+	"details": func(v *Violation) string {
+		return formatDetails(v.Details, "  ")
+	},
+	"toJSON": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if len(runes) <= n {
+			return s
+		}
+		return string(runes[:n]) + "..."
+	},
+}
+
+// ParseTemplate returns a format executing text, a Go text/template, against
+// the report. Along with the methods of Report, like Results and Summary,
+// templates may call:
+//
+//   - results, the results sorted by source;
+//   - violations, every violation of the report as a TemplateViolation,
+//     sorted by source and constraint;
+//   - groupBy and countBy, which group and count violations by constraint,
+//     template, action, engine, resource, kind, namespace or file;
+//   - resourceName, constraint and details, which name resources and
+//     constraints, and format the details of violations;
+//   - toJSON, join, upper, lower, indent and truncate.
+func ParseTemplate(name, text string) (Format, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(w io.Writer, r *Report) error {
+		return t.Execute(w, r)
+	}, nil
+}
+
+func templateViolations(r *Report) []*TemplateViolation {
+	var out []*TemplateViolation
+	for _, result := range sortedResults(r.results) {
+		for _, v := range sortedViolations(result.Violations) {
+			out = append(out, &TemplateViolation{Violation: v, Result: result})
+		}
+	}
+	return out
+}
+
+// groupBy groups violations by key, in the order of the keys.
+func groupBy(key string, violations []*TemplateViolation) ([]*TemplateGroup, error) {
+	keyOf, ok := templateKeys[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q, expected one of %v", key, slices.Sorted(maps.Keys(templateKeys)))
+	}
+	groups := map[string]*TemplateGroup{}
+	for _, v := range violations {
+		k := keyOf(v)
+		group, ok := groups[k]
+		if !ok {
+			group = &TemplateGroup{Key: k}
+			groups[k] = group
+		}
+		group.Violations = append(group.Violations, v)
+	}
+
+	var out []*TemplateGroup
+	for _, k := range slices.Sorted(maps.Keys(groups)) {
+		out = append(out, groups[k])
+	}
+	return out, nil
+}
+
+// countBy counts violations by key. Templates range over maps in the order of
+// their keys.
+func countBy(key string, violations []*TemplateViolation) (map[string]int, error) {
+	groups, err := groupBy(key, violations)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]int{}
+	for _, group := range groups {
+		out[group.Key] = len(group.Violations)
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package reporting_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func executeTemplate(t *testing.T, name, text string) string {
+	t.Helper()
+	format, err := reporting.ParseTemplate(name, text)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, format(&buf, textReport()))
+	return buf.String()
+}
+
+func TestParseTemplate(t *testing.T) {
+	out := executeTemplate(t, "test", `
+{{- range groupBy "constraint" (violations .) }}{{ .Key }}={{ len .Violations }} {{ end }}
+{{- range $action, $count := countBy "action" (violations .) }}{{ $action }}:{{ $count }} {{ end }}
+{{- range results . }}{{ resourceName .Object }} {{ end }}
+{{- .Summary.Failed }}`)
+	assert.Equal(t, ""+
+		"K8sRequiredLabels/app=1 K8sRequiredLabels/owner=1 K8sRequiredLabels/team=2 schema=1 "+
+		"deny:2 dryrun:1 warn:2 "+
+		"v1:Namespace::default apps:v1:Deployment:default:web "+
+		"1", out)
+
+	format, err := reporting.ParseTemplate("test", `{{ groupBy "color" (violations .) }}`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, format(&bytes.Buffer{}, textReport()), `unknown key "color"`)
+
+	_, err = reporting.ParseTemplate("test", `{{ range }}`)
+	assert.Error(t, err)
+}
+
+func TestExampleTemplates(t *testing.T) {
+	paths, err := filepath.Glob("../../examples/templates/*.tmpl")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			text, err := os.ReadFile(path)
+			require.NoError(t, err)
+			out := executeTemplate(t, filepath.Base(path), string(text))
+
+			switch filepath.Base(path) {
+			case "slack.json.tmpl":
+				var message map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(out), &message), out)
+				assert.Len(t, message["blocks"], 5)
+			case "ticket.md.tmpl":
+				assert.Contains(t, out, "### K8sRequiredLabels/owner\n\n- [ ] `apps:v1:Deployment:default:web` in `web.yaml:1:1`: missing owner\n  ```json\n  {\n    \"missing_labels\": [\n")
+				assert.Contains(t, out, "- `broken.yaml`: invalid yaml")
+			case "summary.txt.tmpl":
+				assert.Contains(t, out, "2 resources: 1 passed, 1 failed, 1 errors\n")
+				assert.Contains(t, out, "  (cluster): 1\n  default: 4\n")
+				assert.Contains(t, out, "  DENY: 2\n")
+			}
+		})
+	}
+}
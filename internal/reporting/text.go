@@ -0,0 +1,235 @@
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// How the resources are grouped by Text.
+const (
+	// TextGroupByResource lists each resource followed by its violations.
+	TextGroupByResource = "resource"
+	// TextGroupByConstraint lists each violated constraint followed by the
+	// resources violating it.
+	TextGroupByConstraint = "constraint"
+	// TextGroupByFile lists each input followed by its resources.
+	TextGroupByFile = "file"
+	// TextGroupByNamespace lists each namespace followed by its resources.
+	TextGroupByNamespace = "namespace"
+)
+
+// TextGroups returns the ways resources can be grouped by Text.
+func TextGroups() []string {
+	return []string{TextGroupByResource, TextGroupByConstraint, TextGroupByFile, TextGroupByNamespace}
+}
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// Text returns a format writing the report as lines of text, grouped by one
+// of TextGroups, and followed by a summary with the number of violations per
+// enforcement action. Resources are sorted by source, and violations by
+// constraint, so that the output is the same from run to run. color
+// highlights the status of resources and violations with ANSI escape codes.
+func Text(groupBy string, color bool) (Format, error) {
+	if !slices.Contains(TextGroups(), groupBy) {
+		return nil, fmt.Errorf("unknown group %q, expected one of %v", groupBy, TextGroups())
+	}
+	return func(w io.Writer, r *Report) error {
+		t := &textWriter{}
+		t.b = &strings.Builder{}
+		t.color = color
+		t.write(r, groupBy)
+		_, err := io.WriteString(w, t.b.String())
+		return err
+	}, nil
+}
+
+// WriteText writes the report as lines of text, one per resource followed by
+// one per violation, without color.
+func WriteText(w io.Writer, r *Report) error {
+	format, _ := Text(TextGroupByResource, false)
+	return format(w, r)
+}
+
+type textWriter struct {
+	b     *strings.Builder
+	color bool
+}
+
+func (t *textWriter) write(r *Report, groupBy string) {
+	results := sortedResults(r.results)
+	switch groupBy {
+	case TextGroupByConstraint:
+		t.writeByConstraint(results)
+	case TextGroupByFile:
+		t.writeGroups(results, func(result *Result) string { return result.Source.Input() })
+	case TextGroupByNamespace:
+		t.writeGroups(results, func(result *Result) string {
+			if ns := result.Object.GetNamespace(); ns != "" {
+				return ns
+			}
+			return "(cluster)"
+		})
+	default:
+		for _, result := range results {
+			t.writeResult(result, "")
+		}
+	}
+
+	errs := append([]*InputError{}, r.errors...)
+	sort.SliceStable(errs, func(i, j int) bool { return lessSource(errs[i].Source, errs[j].Source) })
+	for _, e := range errs {
+		fmt.Fprintf(t.b, "%s %s: %s\n", t.paint(colorRed, "ERROR"), e.Source.String(), e.Err)
+	}
+	t.writeSummary(results, len(errs))
+}
+
+func (t *textWriter) writeResult(result *Result, indent string) {
+	status, color := "PASS", colorGreen
+	if result.FailureCount() > 0 {
+		status, color = "FAILED", colorRed
+	}
+	fmt.Fprintf(t.b, "%s%s %s (%s)\n", indent, t.paint(color, status), ResourceName(result.Object), result.Source.String())
+	for _, v := range sortedViolations(result.Violations) {
+		fmt.Fprintf(t.b, "%s  %s %s: %s (%s)\n", indent, t.action(v.EnforcementAction), violationName(v), v.Message, v.Target)
+		t.writeDetails(v, indent+"    ")
+	}
+}
+
+// writeDetails writes the details of v, pretty-printed and indented.
+func (t *textWriter) writeDetails(v *Violation, indent string) {
+	details := formatDetails(v.Details, "  ")
+	if details == "" {
+		return
+	}
+	for _, line := range strings.Split(details, "\n") {
+		fmt.Fprintf(t.b, "%s%s\n", indent, line)
+	}
+}
+
+// writeGroups writes the results under the group returned by key, with groups
+// sorted by name.
+func (t *textWriter) writeGroups(results []*Result, key func(*Result) string) {
+	groups := map[string][]*Result{}
+	for _, result := range results {
+		groups[key(result)] = append(groups[key(result)], result)
+	}
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		fmt.Fprintf(t.b, "%s\n", t.paint(colorBold, name))
+		for _, result := range groups[name] {
+			t.writeResult(result, "  ")
+		}
+	}
+}
+
+func (t *textWriter) writeByConstraint(results []*Result) {
+	type entry struct {
+		result    *Result
+		violation *Violation
+	}
+	groups := map[string][]entry{}
+	for _, result := range results {
+		for _, v := range sortedViolations(result.Violations) {
+			name := violationName(v)
+			groups[name] = append(groups[name], entry{result, v})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		fmt.Fprintf(t.b, "%s\n", t.paint(colorBold, name))
+		for _, e := range groups[name] {
+			fmt.Fprintf(t.b, "  %s %s (%s): %s\n", t.action(e.violation.EnforcementAction), ResourceName(e.result.Object), e.result.Source.String(), e.violation.Message)
+			t.writeDetails(e.violation, "    ")
+		}
+	}
+}
+
+func (t *textWriter) writeSummary(results []*Result, errs int) {
+	var (
+		failed  int
+		actions = map[string]int{}
+	)
+	for _, result := range results {
+		if result.FailureCount() > 0 {
+			failed++
+		}
+		for _, v := range result.Violations {
+			actions[v.EnforcementAction]++
+		}
+	}
+
+	fmt.Fprintf(t.b, "\n%d resources, %d passed, %d failed, %d errors\n", len(results), len(results)-failed, failed, errs)
+	var totals []string
+	for _, action := range slices.Sorted(maps.Keys(actions)) {
+		totals = append(totals, fmt.Sprintf("%s: %d", action, actions[action]))
+	}
+	if len(totals) > 0 {
+		fmt.Fprintf(t.b, "%s\n", strings.Join(totals, ", "))
+	}
+}
+
+func (t *textWriter) action(action string) string {
+	switch action {
+	case "deny":
+		return t.paint(colorRed, "FAILED")
+	case "warn":
+		return t.paint(colorYellow, "WARNING")
+	}
+	return t.paint(colorCyan, strings.ToUpper(action))
+}
+
+func (t *textWriter) paint(color, s string) string {
+	if !t.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func violationName(v *Violation) string {
+	return evaluationName(&Evaluation{Constraint: v.Constraint, ConstraintName: v.ConstraintName})
+}
+
+// sortedResults returns the results sorted by source, then by resource.
+func sortedResults(results []*Result) []*Result {
+	out := append([]*Result{}, results...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Source != out[j].Source {
+			return lessSource(out[i].Source, out[j].Source)
+		}
+		return ResourceName(out[i].Object) < ResourceName(out[j].Object)
+	})
+	return out
+}
+
+// sortedViolations returns the violations sorted by constraint, then by
+// message.
+func sortedViolations(violations []*Violation) []*Violation {
+	out := append([]*Violation{}, violations...)
+	sort.SliceStable(out, func(i, j int) bool {
+		if a, b := violationName(out[i]), violationName(out[j]); a != b {
+			return a < b
+		}
+		return out[i].Message < out[j].Message
+	})
+	return out
+}
+
+func lessSource(a, b Source) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	if a.Variant != b.Variant {
+		return a.Variant < b.Variant
+	}
+	return a.Document < b.Document
+}
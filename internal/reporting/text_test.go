@@ -0,0 +1,126 @@
+package reporting_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func textReport() *reporting.Report {
+	labels := schema.GroupVersionKind{Group: "constraints.gatekeeper.sh", Version: "v1beta1", Kind: "K8sRequiredLabels"}
+
+	report := reporting.New()
+	failed := &reporting.Result{}
+	failed.Object = newObject("apps/v1", "Deployment", "default", "web")
+	failed.Source = reporting.Source{File: "web.yaml", Line: 1, Column: 1}
+	failed.Violations = []*reporting.Violation{
+		{EnforcementAction: "deny", Message: "spec.replica: unknown field", Target: "openapi"},
+		{Constraint: labels, ConstraintName: "team", EnforcementAction: "warn", Message: "missing team", Target: "admission.k8s.gatekeeper.sh"},
+		{Constraint: labels, ConstraintName: "owner", EnforcementAction: "deny", Message: "missing owner", Target: "admission.k8s.gatekeeper.sh", Details: map[string]interface{}{"missing_labels": []interface{}{"owner"}}},
+		{Constraint: labels, ConstraintName: "app", EnforcementAction: "dryrun", Message: "missing app", Target: "admission.k8s.gatekeeper.sh"},
+	}
+	report.AddResult(failed)
+
+	warned := &reporting.Result{}
+	warned.Object = newObject("v1", "Namespace", "", "default")
+	warned.Source = reporting.Source{File: "ns.yaml"}
+	warned.Violations = []*reporting.Violation{
+		{Constraint: labels, ConstraintName: "team", EnforcementAction: "warn", Message: "missing team", Target: "admission.k8s.gatekeeper.sh"},
+	}
+	report.AddResult(warned)
+	report.AddError(reporting.Source{File: "broken.yaml"}, errors.New("invalid yaml"))
+	return report
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, reporting.WriteText(&buf, textReport()))
+	assert.Equal(t, ""+
+		"PASS v1:Namespace::default (ns.yaml)\n"+
+		"  WARNING K8sRequiredLabels/team: missing team (admission.k8s.gatekeeper.sh)\n"+
+		"FAILED apps:v1:Deployment:default:web (web.yaml:1:1)\n"+
+		"  DRYRUN K8sRequiredLabels/app: missing app (admission.k8s.gatekeeper.sh)\n"+
+		"  FAILED K8sRequiredLabels/owner: missing owner (admission.k8s.gatekeeper.sh)\n"+
+		"    {\n"+
+		"      \"missing_labels\": [\n"+
+		"        \"owner\"\n"+
+		"      ]\n"+
+		"    }\n"+
+		"  WARNING K8sRequiredLabels/team: missing team (admission.k8s.gatekeeper.sh)\n"+
+		"  FAILED schema: spec.replica: unknown field (openapi)\n"+
+		"ERROR broken.yaml: invalid yaml\n"+
+		"\n"+
+		"2 resources, 1 passed, 1 failed, 1 errors\n"+
+		"deny: 2, dryrun: 1, warn: 2\n", buf.String())
+}
+
+func TestTextGroupBy(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		want    string
+	}{
+		{
+			groupBy: reporting.TextGroupByConstraint,
+			want: "" +
+				"K8sRequiredLabels/app\n" +
+				"  DRYRUN apps:v1:Deployment:default:web (web.yaml:1:1): missing app\n" +
+				"K8sRequiredLabels/owner\n" +
+				"  FAILED apps:v1:Deployment:default:web (web.yaml:1:1): missing owner\n" +
+				"    {\n" +
+				"      \"missing_labels\": [\n" +
+				"        \"owner\"\n" +
+				"      ]\n" +
+				"    }\n" +
+				"K8sRequiredLabels/team\n" +
+				"  WARNING v1:Namespace::default (ns.yaml): missing team\n" +
+				"  WARNING apps:v1:Deployment:default:web (web.yaml:1:1): missing team\n" +
+				"schema\n" +
+				"  FAILED apps:v1:Deployment:default:web (web.yaml:1:1): spec.replica: unknown field\n",
+		},
+		{
+			groupBy: reporting.TextGroupByNamespace,
+			want: "" +
+				"(cluster)\n" +
+				"  PASS v1:Namespace::default (ns.yaml)\n" +
+				"    WARNING K8sRequiredLabels/team: missing team (admission.k8s.gatekeeper.sh)\n" +
+				"default\n" +
+				"  FAILED apps:v1:Deployment:default:web (web.yaml:1:1)\n",
+		},
+		{
+			groupBy: reporting.TextGroupByFile,
+			want: "" +
+				"ns.yaml\n" +
+				"  PASS v1:Namespace::default (ns.yaml)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			format, err := reporting.Text(tt.groupBy, false)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, format(&buf, textReport()))
+			assert.Contains(t, buf.String(), tt.want)
+			assert.Contains(t, buf.String(), "\n2 resources, 1 passed, 1 failed, 1 errors\n")
+		})
+	}
+
+	_, err := reporting.Text("kind", false)
+	assert.Error(t, err)
+}
+
+func TestTextColor(t *testing.T) {
+	format, err := reporting.Text(reporting.TextGroupByResource, true)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, format(&buf, textReport()))
+	assert.Contains(t, buf.String(), "\033[31mFAILED\033[0m apps:v1:Deployment:default:web")
+	assert.Contains(t, buf.String(), "\033[32mPASS\033[0m v1:Namespace::default")
+	assert.Contains(t, buf.String(), "  \033[33mWARNING\033[0m K8sRequiredLabels/team")
+}
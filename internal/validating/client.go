@@ -1,21 +1,25 @@
 package validating
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/limoges/gatepeeker/internal/bundle"
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/defaulting"
+	"github.com/limoges/gatepeeker/internal/openapi"
 	"github.com/limoges/gatepeeker/internal/reporting"
 	opaclient "github.com/open-policy-agent/frameworks/constraint/pkg/client"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client/drivers/rego"
 	"github.com/open-policy-agent/frameworks/constraint/pkg/client/reviews"
+	"github.com/open-policy-agent/frameworks/constraint/pkg/core/templates"
 	rtypes "github.com/open-policy-agent/frameworks/constraint/pkg/types"
 	"github.com/open-policy-agent/gatekeeper/v3/apis"
 	"github.com/open-policy-agent/gatekeeper/v3/pkg/drivers/k8scel"
 	"github.com/open-policy-agent/gatekeeper/v3/pkg/gator"
-	"github.com/open-policy-agent/gatekeeper/v3/pkg/gator/reader"
 	"github.com/open-policy-agent/gatekeeper/v3/pkg/target"
 	"github.com/open-policy-agent/gatekeeper/v3/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
@@ -37,11 +41,65 @@ func init() {
 }
 
 type Client struct {
-	client gator.Client
-	bundle *bundle.Bundle
+	client           gator.Client
+	bundle           *bundle.Bundle
+	strict           bool
+	applyDefaults    bool
+	detectDuplicates bool
+	schemas          *openapi.Validator
+	redactor         *reporting.Redactor
+	// templates maps the kind of constraints to the name of their template.
+	templates map[string]string
+	// engines maps the kind of constraints to the engine evaluating them.
+	engines map[string]string
 }
 
-func NewClientWithBundle(ctx context.Context, b *bundle.Bundle) (*Client, error) {
+// Option configures a Client.
+type Option func(*Client)
+
+// Strict makes Validate fail when a document can't be decoded or lacks an
+// apiVersion or kind, instead of skipping it.
+func Strict(strict bool) Option {
+	return func(c *Client) {
+		c.strict = strict
+	}
+}
+
+// ApplyDefaults makes Validate apply the defaults set by the API server to
+// known resources before reviewing them, as the admission webhook of a
+// cluster would see them.
+func ApplyDefaults(applyDefaults bool) Option {
+	return func(c *Client) {
+		c.applyDefaults = applyDefaults
+	}
+}
+
+// DetectDuplicates makes Validate deny the resources sharing their apiVersion,
+// kind, namespace and name with a previous resource of the same manifests, as
+// rendered outputs which would overwrite each other when applied.
+func DetectDuplicates(detectDuplicates bool) Option {
+	return func(c *Client) {
+		c.detectDuplicates = detectDuplicates
+	}
+}
+
+// Schemas makes Validate check resources against their OpenAPI schema before
+// reviewing them. Schema errors are reported as denials.
+func Schemas(v *openapi.Validator) Option {
+	return func(c *Client) {
+		c.schemas = v
+	}
+}
+
+// Redact makes Validate mask the sensitive values found by r in the errors it
+// logs, which are written before the report is redacted.
+func Redact(r *reporting.Redactor) Option {
+	return func(c *Client) {
+		c.redactor = r
+	}
+}
+
+func NewClientWithBundle(ctx context.Context, b *bundle.Bundle, opts ...Option) (*Client, error) {
 	client, err := newGatorClient()
 	if err != nil {
 		return nil, err
@@ -50,28 +108,37 @@ func NewClientWithBundle(ctx context.Context, b *bundle.Bundle) (*Client, error)
 	for _, v := range b.GetConstraintTemplates() {
 		responses, err := client.AddTemplate(ctx, v.GetObject())
 		if err != nil {
-			panic(fmt.Sprintf("failed to add template: %s", err))
+			return nil, fmt.Errorf("failed to add template %s: %w", v.GetName(), err)
 		}
 
 		for _, result := range responses.Results() {
-			fmt.Println(result.Target, result.Msg)
+			slog.Debug("added template", "name", v.GetName(), "target", result.Target, "msg", result.Msg)
 		}
 	}
 
 	for _, v := range b.GetConstraints() {
 		responses, err := client.AddConstraint(ctx, v.GetObject())
 		if err != nil {
-			panic(fmt.Sprintf("failed to add constraint: %s", err))
+			return nil, fmt.Errorf("failed to add constraint %s: %w", v.GetName(), err)
 		}
 
 		for _, result := range responses.Results() {
-			fmt.Println(result.Target, result.Msg)
+			slog.Debug("added constraint", "name", v.GetName(), "target", result.Target, "msg", result.Msg)
 		}
 	}
 
 	c := &Client{}
 	c.client = client
 	c.bundle = b
+	c.templates = make(map[string]string)
+	c.engines = make(map[string]string)
+	for _, t := range b.GetConstraintTemplates() {
+		c.templates[t.Spec.CRD.Spec.Names.Kind] = t.GetName()
+		c.engines[t.Spec.CRD.Spec.Names.Kind] = templateEngine(t.GetObject())
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
 	return c, nil
 }
 
@@ -95,7 +162,9 @@ func newGatorClient() (gator.Client, error) {
 	return opaclient.NewClient(opts...)
 }
 
-func (c *Client) Validate(ctx context.Context, manifestsYAML []byte) (*reporting.Report, error) {
+// Validate reviews every resource in manifestsYAML. source describes where
+// the manifests were read from and is recorded on each result.
+func (c *Client) Validate(ctx context.Context, source reporting.Source, manifestsYAML []byte) (*reporting.Report, error) {
 
 	if c.bundle == nil {
 		return nil, errors.New("no constraints or templates to validate")
@@ -108,34 +177,117 @@ func (c *Client) Validate(ctx context.Context, manifestsYAML []byte) (*reporting
 		return nil, errors.New("no templates to validate")
 	}
 
-	resources, err := reader.ReadK8sResources(bytes.NewReader(manifestsYAML))
+	report := reporting.New()
+	resources, err := c.readResources(report, source, manifestsYAML)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	report := reporting.New()
-	for _, v := range resources {
+	var results []*reporting.Result
+	for _, doc := range resources {
+		v := doc.Object
+		if c.applyDefaults {
+			defaulted, err := defaulting.Default(v)
+			if err != nil {
+				slog.Warn("failed to apply defaults, reviewing the resource as is", "source", source.String(), "line", doc.Line, "error", c.redactor.RedactMessage(err.Error(), v))
+			} else {
+				v = defaulted
+			}
+		}
 		req, err := unstructuredToAdmissionRequest(v)
 		if err != nil {
-			panic(err)
+			return nil, fmt.Errorf("failed to review %s: %w", reporting.ResourceName(v), err)
 		}
 
+		start := time.Now()
 		resp, err := c.client.Review(ctx, req, reviews.EnforcementPoint(util.WebhookEnforcementPoint), reviews.Tracing(true))
 		if err != nil {
-			panic(fmt.Sprintf("failed review: %s", err))
+			return nil, fmt.Errorf("failed to review %s: %w", reporting.ResourceName(v), err)
 		}
+		duration := time.Since(start)
 
-		denials, warnings := getValidationMessages(resp.Results(), req)
 		result := &reporting.Result{}
 		result.Object = v
-		result.Denials = denials
-		result.Warnings = warnings
+		result.Source = source
+		result.Source.Template = doc.Template
+		result.Source.Document = doc.Index
+		result.Source.Item = doc.Item
+		result.Source.Line = doc.Line
+		result.Source.Column = doc.Column
+		result.Violations = c.getViolations(resp.Results())
+		result.Evaluations = c.getEvaluations(req)
+		result.Start = start
+		result.Duration = duration
+		c.checkSchema(report, result)
+		results = append(results, result)
+	}
+
+	c.markDuplicates(results)
+	for _, result := range results {
 		report.AddResult(result)
 	}
 
 	return report, nil
 }
 
+// readResources decodes the documents of manifestsYAML which can be reviewed.
+// The documents which can't be decoded, or which lack an apiVersion or kind,
+// are recorded as errors of report, unless in strict mode, where they are
+// returned as an error instead.
+func (c *Client) readResources(report *reporting.Report, source reporting.Source, manifestsYAML []byte) ([]*decoding.Document, error) {
+	documents, err := decoding.Decode(manifestsYAML)
+	if err != nil && !c.strict {
+		slog.Error("failed to decode resources", "source", source.String(), "error", c.redactor.RedactMessage(err.Error(), nil))
+	}
+
+	var (
+		resources []*decoding.Document
+		rejected  = []error{err}
+	)
+	for _, doc := range documents {
+		v := doc.Object
+		if v.GetAPIVersion() == "" || v.GetKind() == "" {
+			if !c.strict {
+				slog.Warn("skipping document without apiVersion or kind", "source", source.String(), "document", doc.Index, "line", doc.Line)
+			}
+			rejected = append(rejected, &decoding.DocumentError{Index: doc.Index, Line: doc.Line, Err: errors.New("missing apiVersion or kind")})
+			continue
+		}
+		resources = append(resources, doc)
+	}
+	if c.strict {
+		if err := errors.Join(rejected...); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, docErr := range decoding.Errors(errors.Join(rejected...)) {
+		errSource := source
+		errSource.Document = docErr.Index
+		errSource.Line = docErr.Line
+		report.AddError(errSource, docErr.Err)
+	}
+	return resources, nil
+}
+
+// checkSchema adds the schema violations of the resource of result, and the
+// evaluation of its schema, ahead of those of the constraints. A schema which
+// can't be read is recorded as an error of report, and the resource isn't
+// reported as complying with it.
+func (c *Client) checkSchema(report *reporting.Report, result *reporting.Result) {
+	if c.schemas == nil {
+		return
+	}
+	errs, err := c.schemas.Validate(result.Object)
+	if err != nil {
+		slog.Error("failed to validate schema", "source", result.Source.String(), "error", err)
+		report.AddError(result.Source, err)
+		return
+	}
+	result.Violations = append(getSchemaViolations(errs), result.Violations...)
+	result.Evaluations = append([]*reporting.Evaluation{{}}, result.Evaluations...)
+}
+
 func unstructuredToAdmissionRequest(obj *unstructured.Unstructured) (*admissionv1.AdmissionRequest, error) {
 	resourceJSON, err := obj.MarshalJSON()
 	if err != nil {
@@ -158,33 +310,128 @@ func unstructuredToAdmissionRequest(obj *unstructured.Unstructured) (*admissionv
 	return req, nil
 }
 
-func getValidationMessages(results []*rtypes.Result, req *admissionv1.AdmissionRequest) (deny, warn []string) {
+func getSchemaViolations(errs []*openapi.FieldError) (out []*reporting.Violation) {
+	for _, err := range errs {
+		v := &reporting.Violation{}
+		v.EnforcementAction = "deny"
+		v.Message = err.Error()
+		v.Target = "openapi"
+		v.Engine = reporting.EngineOpenAPI
+		v.Details = map[string]interface{}{"field": err.Path}
+		out = append(out, v)
+	}
+	return out
+}
+
+// markDuplicates adds the duplicates check to results, denying the resources
+// sharing their identity with a previous one. It does nothing unless
+// DetectDuplicates is set.
+func (c *Client) markDuplicates(results []*reporting.Result) {
+	if !c.detectDuplicates {
+		return
+	}
+	first := map[string]*reporting.Result{}
+	for _, result := range results {
+		result.Evaluations = append(result.Evaluations, &reporting.Evaluation{ConstraintName: reporting.CheckDuplicates})
+
+		name := reporting.ResourceName(result.Object)
+		original, ok := first[name]
+		if !ok {
+			first[name] = result
+			continue
+		}
+		v := &reporting.Violation{}
+		v.ConstraintName = reporting.CheckDuplicates
+		v.EnforcementAction = "deny"
+		v.Message = fmt.Sprintf("duplicate of the resource of document %d (%s)", original.Source.Document, original.Source.String())
+		v.Target = "gatepeeker"
+		v.Details = map[string]interface{}{"document": original.Source.Document}
+		if original.Source.Item > 0 {
+			v.Details["item"] = original.Source.Item
+		}
+		result.Violations = append(result.Violations, v)
+	}
+}
+
+// getEvaluations returns every constraint of the bundle, skipping those which
+// don't match req. Constraints whose match can't be computed are kept.
+func (c *Client) getEvaluations(req *admissionv1.AdmissionRequest) (out []*reporting.Evaluation) {
+	handled, review, err := k8starget.HandleReview(req)
+	if err != nil || !handled {
+		slog.Debug("failed to handle review for matching", "name", req.Name, "error", err)
+	}
+
+	for _, constraint := range c.bundle.GetConstraints() {
+		obj := constraint.GetObject()
+		e := &reporting.Evaluation{}
+		e.Constraint = obj.GroupVersionKind()
+		e.ConstraintName = obj.GetName()
+		e.Template = c.templates[obj.GetKind()]
+		out = append(out, e)
+
+		if err != nil || !handled {
+			continue
+		}
+		matcher, err := k8starget.ToMatcher(obj)
+		if err != nil {
+			slog.Debug("failed to build constraint matcher", "constraint", obj.GetName(), "error", err)
+			continue
+		}
+		matched, err := matcher.Match(review)
+		if err != nil {
+			slog.Debug("failed to match constraint", "constraint", obj.GetName(), "name", req.Name, "error", err)
+			continue
+		}
+		e.Skipped = !matched
+	}
+	return out
+}
+
+func (c *Client) getViolations(results []*rtypes.Result) (out []*reporting.Violation) {
 	for _, result := range results {
-		constraint := fmt.Sprintf("%s/%s/%s:%s",
-			result.Constraint.GroupVersionKind().Group,
-			result.Constraint.GroupVersionKind().Version,
-			result.Constraint.GetKind(),
-			result.Constraint.GetName(),
-		)
-		resource := fmt.Sprintf("%s/%s/%s:%s/%s",
-			req.Kind.Group,
-			req.Kind.Version,
-			req.Kind.Kind,
-			req.Namespace,
-			req.Name,
-		)
-
-		action := result.EnforcementAction
-		switch action {
-		case "deny":
-			msg := fmt.Sprintf("%s %s %s: %s (%s)\n", constraint, action, resource, result.Msg, result.Target)
-			deny = append(deny, msg)
-		case "warn":
-			msg := fmt.Sprintf("%s %s %s: %s (%s)\n", constraint, action, resource, result.Msg, result.Target)
-			warn = append(warn, msg)
-		default:
-			panic(fmt.Sprintf("unknown r.EnforcementAction: %s", action))
-		}
-	}
-	return
+		v := &reporting.Violation{}
+		v.Constraint = result.Constraint.GroupVersionKind()
+		v.ConstraintName = result.Constraint.GetName()
+		v.Template = c.templates[result.Constraint.GetKind()]
+		v.EnforcementAction = result.EnforcementAction
+		v.Message = result.Msg
+		v.Target = result.Target
+		v.Engine = c.engines[result.Constraint.GetKind()]
+		v.Details = violationDetails(result.Metadata["details"])
+		out = append(out, v)
+	}
+	return out
+}
+
+// violationDetails returns the details of a violation as a map. Details which
+// aren't an object, which templates may return, are kept under "details".
+func violationDetails(details interface{}) map[string]interface{} {
+	switch d := details.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		return d
+	}
+	return map[string]interface{}{"details": details}
+}
+
+// templateEngine returns the engine evaluating the constraints of t. Like the
+// drivers of the client, Rego is preferred when a template holds code for
+// both engines.
+func templateEngine(t *templates.ConstraintTemplate) string {
+	var engine string
+	for _, target := range t.Spec.Targets {
+		if target.Rego != "" {
+			return reporting.EngineRego
+		}
+		for _, code := range target.Code {
+			switch code.Engine {
+			case "Rego":
+				return reporting.EngineRego
+			case "K8sNativeValidation":
+				engine = reporting.EngineCEL
+			}
+		}
+	}
+	return engine
 }
@@ -0,0 +1,193 @@
+package validating_test
+
+import (
+	"testing"
+
+	"github.com/limoges/gatepeeker/internal/decoding"
+	"github.com/limoges/gatepeeker/internal/openapi"
+	"github.com/limoges/gatepeeker/internal/reporting"
+	"github.com/limoges/gatepeeker/internal/validating"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const manifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+metadata:
+  name: no-kind
+---
+apiVersion: v1
+kind: [
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: three
+`
+
+func TestReadResources(t *testing.T) {
+	source := reporting.Source{File: "manifests.yaml"}
+
+	report := reporting.New()
+	docs, err := validating.ReadResources(validating.NewTestClient(), report, source, []byte(manifests))
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "settings", docs[0].Object.GetName())
+	assert.Equal(t, "web", docs[1].Object.GetName())
+
+	// Documents which can't be reviewed are kept as errors of the report.
+	require.Len(t, report.Errors(), 2)
+	assert.Equal(t, 2, report.Errors()[0].Source.Document)
+	assert.Equal(t, 1, report.Errors()[1].Source.Document)
+	assert.EqualError(t, report.Errors()[1].Err, "missing apiVersion or kind")
+
+	// In strict mode, they fail the input instead.
+	report = reporting.New()
+	_, err = validating.ReadResources(validating.NewTestClient(validating.Strict(true)), report, source, []byte(manifests))
+	assert.Len(t, decoding.Errors(err), 2)
+	assert.Empty(t, report.Errors())
+}
+
+func TestCheckSchema(t *testing.T) {
+	schemas, err := openapi.NewValidator("", openapi.DefaultVersion)
+	require.NoError(t, err)
+	client := validating.NewTestClient(validating.Schemas(schemas))
+
+	report := reporting.New()
+	docs, err := validating.ReadResources(client, report, reporting.Source{File: "manifests.yaml"}, []byte(manifests))
+	require.NoError(t, err)
+
+	result := &reporting.Result{}
+	result.Object = docs[1].Object
+	result.Violations = []*reporting.Violation{{ConstraintName: "owner", EnforcementAction: "deny", Message: "missing owner"}}
+	result.Evaluations = []*reporting.Evaluation{{ConstraintName: "owner"}}
+	validating.CheckSchema(client, report, result)
+
+	// The schema comes first, ahead of the constraints.
+	var messages []string
+	for _, v := range result.Violations[:len(result.Violations)-1] {
+		assert.Equal(t, reporting.EngineOpenAPI, v.Engine)
+		messages = append(messages, v.Message)
+	}
+	assert.Contains(t, messages, `spec.replicas: expected an integer, found "three"`)
+	assert.Equal(t, "owner", result.Violations[len(result.Violations)-1].ConstraintName)
+	require.Len(t, result.Evaluations, 2)
+	assert.Equal(t, "", result.Evaluations[0].ConstraintName)
+	assert.Equal(t, "owner", result.Evaluations[1].ConstraintName)
+
+	// Without --validate-schemas, results are left as they are.
+	result.Violations = result.Violations[len(result.Violations)-1:]
+	result.Evaluations = result.Evaluations[1:]
+	validating.CheckSchema(validating.NewTestClient(), report, result)
+	assert.Len(t, result.Violations, 1)
+	assert.Len(t, result.Evaluations, 1)
+}
+
+func TestMarkDuplicates(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		enabled   bool
+		manifests string
+		denied    []bool
+	}{
+		{
+			name:    "same input",
+			enabled: true,
+			manifests: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`,
+			denied: []bool{false, false, true},
+		},
+		{
+			name:    "list items",
+			enabled: true,
+			manifests: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+`,
+			denied: []bool{false, true},
+		},
+		{
+			name:    "disabled",
+			enabled: false,
+			manifests: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`,
+			denied: []bool{false, false},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := decoding.Decode([]byte(tt.manifests))
+			require.NoError(t, err)
+
+			var results []*reporting.Result
+			for _, doc := range docs {
+				result := &reporting.Result{}
+				result.Object = doc.Object
+				result.Source = reporting.Source{File: "manifests.yaml", Document: doc.Index, Item: doc.Item}
+				results = append(results, result)
+			}
+			validating.MarkDuplicates(validating.NewTestClient(validating.DetectDuplicates(tt.enabled)), results)
+
+			var denied []bool
+			for _, result := range results {
+				denied = append(denied, result.FailureCount() > 0)
+				if tt.enabled {
+					require.Len(t, result.Evaluations, 1)
+					assert.Equal(t, reporting.CheckDuplicates, result.Evaluations[0].ConstraintName)
+				} else {
+					assert.Empty(t, result.Evaluations)
+				}
+			}
+			assert.Equal(t, tt.denied, denied)
+		})
+	}
+
+	// The original of a duplicate List item is named by its item.
+	docs, err := decoding.Decode([]byte(`apiVersion: v1
+kind: List
+items:
+- {apiVersion: v1, kind: ConfigMap, metadata: {name: settings}}
+- {apiVersion: v1, kind: ConfigMap, metadata: {name: settings}}
+`))
+	require.NoError(t, err)
+	var results []*reporting.Result
+	for _, doc := range docs {
+		results = append(results, &reporting.Result{Object: doc.Object, Source: reporting.Source{Document: doc.Index, Item: doc.Item}})
+	}
+	validating.MarkDuplicates(validating.NewTestClient(validating.DetectDuplicates(true)), results)
+	require.Len(t, results[1].Violations, 1)
+	assert.Equal(t, map[string]interface{}{"document": 0, "item": 1}, results[1].Violations[0].Details)
+}
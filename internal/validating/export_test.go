@@ -0,0 +1,18 @@
+package validating
+
+// The steps of Validate which don't need a review, for tests.
+var (
+	ReadResources  = (*Client).readResources
+	CheckSchema    = (*Client).checkSchema
+	MarkDuplicates = (*Client).markDuplicates
+)
+
+// NewTestClient returns a Client without constraints, which can only run the
+// steps of Validate which don't review resources.
+func NewTestClient(opts ...Option) *Client {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}